@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+)
+
+func (a *app) createAccount(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("accounts create", flag.ContinueOnError)
+	owner := flags.String("owner", "", "customer the account belongs to")
+	currency := flags.String("currency", "USD", "currency of the account")
+	accountType := flags.String("type", models.AccountTypeChecking, "account type")
+	balance := flags.Int64("balance", 0, "opening balance in minor units")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *owner == "" {
+		return fmt.Errorf("-owner must be set")
+	}
+
+	account, err := a.services.Account.CreateAccount(ctx, *owner, *currency, *accountType, *balance)
+	if err != nil {
+		return err
+	}
+	return printRow(a.out, accountColumns, *account)
+}
+
+func (a *app) listAccounts(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("accounts list", flag.ContinueOnError)
+	owner := flags.String("owner", "", "list only this customer's accounts")
+	limit := flags.Int("limit", 100, "accounts per page, at most 100")
+	cursor := flags.String("cursor", "", "cursor of the page to list, printed after the previous one")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	req := services.PageRequest{PageSize: *limit, Cursor: *cursor}
+	var page *services.Page[models.Account]
+	var err error
+	if *owner != "" {
+		page, err = a.services.Account.GetAccountsByOwner(ctx, *owner, req)
+	} else {
+		page, err = a.services.Admin.ListAccounts(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	if err := printRows(a.out, accountColumns, page.Items); err != nil {
+		return err
+	}
+	printNextCursor(page.NextCursor)
+	return nil
+}
+
+func (a *app) freezeAccount(ctx context.Context, args []string) error {
+	return a.setAccountStatus(ctx, "accounts freeze", args, a.services.Admin.FreezeAccount)
+}
+
+func (a *app) unfreezeAccount(ctx context.Context, args []string) error {
+	return a.setAccountStatus(ctx, "accounts unfreeze", args, a.services.Admin.UnfreezeAccount)
+}
+
+func (a *app) setAccountStatus(ctx context.Context, name string, args []string, set func(ctx context.Context, id int64, reason string) (*models.Account, error)) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	reason := flags.String("reason", "", "why, recorded in the admin action log")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	account, err := set(ctx, id, *reason)
+	if err != nil {
+		return err
+	}
+	return printRow(a.out, accountColumns, *account)
+}
+
+// parse parses a command's flags, which must be followed by exactly
+// positional arguments
+func parse(flags *flag.FlagSet, args []string, positional int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != positional {
+		return errUsage
+	}
+	return nil
+}
+
+func parseID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid ID %q", arg)
+	}
+	return id, nil
+}
+
+// printNextCursor tells how to list the next page, if there is one. It
+// goes to stderr so that output stays a single table or JSON document.
+func printNextCursor(cursor string) {
+	if cursor != "" {
+		fmt.Fprintf(os.Stderr, "more results: rerun with -cursor %s\n", cursor)
+	}
+}
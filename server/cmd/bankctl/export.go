@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// export writes every account, transfer or entry to stdout as CSV or as
+// JSON lines, one row per line, so exports of any size stream
+func (a *app) export(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "csv or json")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown export format %q", *format)
+	}
+
+	switch flags.Arg(0) {
+	case "accounts":
+		w := newExportWriter(a, *format, accountColumns)
+		return w.finish(a.services.Admin.ExportAccounts(ctx, w.write))
+	case "transfers":
+		w := newExportWriter(a, *format, transferColumns)
+		return w.finish(a.services.Admin.ExportTransfers(ctx, w.write))
+	case "entries":
+		w := newExportWriter(a, *format, entryColumns)
+		return w.finish(a.services.Admin.ExportEntries(ctx, w.write))
+	default:
+		return fmt.Errorf("cannot export %q; choose accounts, transfers or entries", flags.Arg(0))
+	}
+}
+
+// exportWriter writes exported rows as they are read
+type exportWriter[T any] struct {
+	columns []column[T]
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+func newExportWriter[T any](a *app, format string, columns []column[T]) *exportWriter[T] {
+	w := &exportWriter[T]{columns: columns}
+	if format == "json" {
+		w.json = json.NewEncoder(a.out.w)
+		return w
+	}
+
+	w.csv = csv.NewWriter(a.out.w)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	// Write errors are sticky and reported by finish
+	_ = w.csv.Write(header)
+	return w
+}
+
+func (w *exportWriter[T]) write(row T) error {
+	if w.json != nil {
+		return w.json.Encode(record(w.columns, row))
+	}
+	return w.csv.Write(cells(w.columns, row))
+}
+
+// finish flushes buffered rows and returns the export's error, if any
+func (w *exportWriter[T]) finish(err error) error {
+	if w.csv != nil {
+		w.csv.Flush()
+		err = errors.Join(err, w.csv.Error())
+	}
+	return err
+}
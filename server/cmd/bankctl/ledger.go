@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+)
+
+// errUnbalanced makes reconcile exit with an error, for use from cron jobs
+var errUnbalanced = errors.New("ledger is out of balance")
+
+// statement prints an account's entries in a period, oldest first, with
+// the balance after each
+func (a *app) statement(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	from := flags.String("from", "", "first day or time to include, such as 2024-01-31")
+	to := flags.String("to", "", "day or time to stop before")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var filter repositories.EntryFilter
+	if filter.CreatedFrom, err = parseTime(*from); err != nil {
+		return err
+	}
+	if filter.CreatedTo, err = parseTime(*to); err != nil {
+		return err
+	}
+
+	var entries []models.Entry
+	req := services.PageRequest{PageSize: 100, Sort: repositories.SortCreatedAt, Ascending: true}
+	for {
+		page, err := a.services.Entry.ListEntries(ctx, id, filter, req)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+	return printRows(a.out, statementColumns, entries)
+}
+
+// parseTime accepts a date or an RFC 3339 time; empty means unbounded
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want a date such as 2024-01-31 or an RFC 3339 time", value)
+	}
+	return t, nil
+}
+
+// reconcile checks the ledger and lists what does not add up
+func (a *app) reconcile(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	result, err := a.services.Admin.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+
+	if a.out.json {
+		if err := a.out.encode(result); err != nil {
+			return err
+		}
+	} else if !result.Balanced() {
+		if len(result.Accounts) > 0 {
+			fmt.Fprintln(a.out.w, "Accounts whose balance differs from their entries:")
+			if err := printRows(a.out, mismatchColumns, result.Accounts); err != nil {
+				return err
+			}
+		}
+		if len(result.Transfers) > 0 {
+			fmt.Fprintln(a.out.w, "Transfers whose entries do not balance:")
+			if err := printRows(a.out, unbalancedColumns, result.Transfers); err != nil {
+				return err
+			}
+		}
+	} else {
+		fmt.Fprintln(a.out.w, "Ledger is balanced")
+	}
+
+	if !result.Balanced() {
+		return errUnbalanced
+	}
+	return nil
+}
+
+var mismatchColumns = []column[repositories.BalanceMismatch]{
+	{"account_id", func(m repositories.BalanceMismatch) any { return m.AccountID }},
+	{"balance", func(m repositories.BalanceMismatch) any { return m.Balance }},
+	{"ledger", func(m repositories.BalanceMismatch) any { return m.Ledger }},
+	{"difference", func(m repositories.BalanceMismatch) any { return m.Balance - m.Ledger }},
+}
+
+var unbalancedColumns = []column[repositories.UnbalancedTransfer]{
+	{"transfer_id", func(t repositories.UnbalancedTransfer) any { return t.TransferID }},
+	{"entries", func(t repositories.UnbalancedTransfer) any { return t.Entries }},
+	{"net", func(t repositories.UnbalancedTransfer) any { return t.Net }},
+}
@@ -0,0 +1,151 @@
+// Command bankctl runs bank operations from the command line. It uses the
+// same services as the server, connecting to the database configured by
+// the server's environment, and acts as a staff member whose role is
+// checked against the permission matrix and recorded in the audit and
+// admin action logs. The actor must be named with -actor, and the role
+// is support unless -role asks for more; both are taken on trust, as
+// anyone who can run bankctl holds the database credentials anyway.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"simple_bank/server/config"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/database"
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/requestinfo"
+	"simple_bank/server/internal/services"
+	"simple_bank/server/internal/stream"
+)
+
+const usage = `usage: bankctl [flags] <command> [arguments]
+
+commands:
+  accounts create -owner OWNER [-currency USD] [-type checking] [-balance N]
+  accounts list [-owner OWNER] [-limit N] [-cursor CURSOR]
+  accounts freeze -reason REASON ACCOUNT_ID
+  accounts unfreeze -reason REASON ACCOUNT_ID
+  transfers create -from ACCOUNT_ID -to ACCOUNT_ID -amount N
+  transfers reverse -reason REASON TRANSFER_ID
+  statement [-from DATE] [-to DATE] ACCOUNT_ID
+  reconcile
+  export [-format csv|json] accounts|transfers|entries
+
+flags:`
+
+// errUsage is returned for commands and arguments bankctl does not know
+var errUsage = errors.New("invalid usage; run bankctl -h for help")
+
+// app holds what commands need to run
+type app struct {
+	services *services.Services
+	out      printer
+}
+
+// command runs one bankctl command with the arguments that follow its name
+type command func(ctx context.Context, args []string) error
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	output := flag.String("o", "table", "output format: table or json")
+	actor := flag.String("actor", "", "name recorded as the actor in the audit and admin action logs (required)")
+	role := flag.String("role", string(auth.RoleSupport), "staff role to act with: support, operator or admin")
+	flag.Parse()
+
+	if err := run(*output, *actor, auth.Role(*role), flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "bankctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(output, actor string, role auth.Role, args []string) error {
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unknown output format %q", output)
+	}
+	if actor == "" {
+		return errors.New("-actor must be set")
+	}
+	if !role.IsStaff() {
+		return fmt.Errorf("%q is not a staff role", role)
+	}
+
+	a := &app{out: printer{w: os.Stdout, json: output == "json"}}
+	cmd, args, err := a.lookup(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	// Logs go to stderr so that output can be piped
+	slog.SetDefault(logging.New(os.Stderr, cfg.LogLevels, []byte(cfg.LogPseudonymKey)))
+
+	if err := database.ConnectDB(cfg); err != nil {
+		return err
+	}
+	db := database.GetDB()
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if _, err := health.Migrations(sqlDB, database.SchemaVersion)(context.Background()); err != nil {
+		return fmt.Errorf("database schema does not match this build: %w", err)
+	}
+
+	timeouts := repositories.Timeouts{
+		Read:        cfg.DBReadTimeout,
+		Write:       cfg.DBWriteTimeout,
+		Transaction: cfg.DBTransactionTimeout,
+	}
+	a.services = services.NewServices(repositories.NewRepository(db, timeouts), repositories.NewUnitOfWork(db, timeouts),
+		stream.NewBroker(), services.AccountTypePolicy{Multiple: cfg.AccountMultipleTypes},
+		services.WebhookPolicy{AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: actor, Role: role})
+	ctx = requestinfo.WithInfo(ctx, requestinfo.Info{RequestID: requestinfo.NewID()})
+	return cmd(ctx, args)
+}
+
+// lookup finds the command named by the leading arguments
+func (a *app) lookup(args []string) (command, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, errUsage
+	}
+	commands := map[string]command{
+		"accounts create":   a.createAccount,
+		"accounts list":     a.listAccounts,
+		"accounts freeze":   a.freezeAccount,
+		"accounts unfreeze": a.unfreezeAccount,
+		"transfers create":  a.createTransfer,
+		"transfers reverse": a.reverseTransfer,
+		"statement":         a.statement,
+		"reconcile":         a.reconcile,
+		"export":            a.export,
+	}
+
+	name, args := args[0], args[1:]
+	if (name == "accounts" || name == "transfers") && len(args) > 0 {
+		name, args = name+" "+args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return nil, nil, errUsage
+	}
+	return cmd, args, nil
+}
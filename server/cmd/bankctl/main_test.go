@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"simple_bank/server/internal/auth"
+)
+
+func TestLookupSplitsCommandsFromTheirArguments(t *testing.T) {
+	a := &app{}
+	for _, tc := range []struct {
+		args []string
+		rest []string
+	}{
+		{[]string{"accounts", "freeze", "-reason", "fraud", "7"}, []string{"-reason", "fraud", "7"}},
+		{[]string{"transfers", "reverse", "3"}, []string{"3"}},
+		{[]string{"statement", "-from", "2024-01-01", "1"}, []string{"-from", "2024-01-01", "1"}},
+		{[]string{"reconcile"}, []string{}},
+	} {
+		cmd, rest, err := a.lookup(tc.args)
+		if err != nil || cmd == nil || !slices.Equal(rest, tc.rest) {
+			t.Errorf("lookup(%q) = %v, %q, want its command and %q", tc.args, err, rest, tc.rest)
+		}
+	}
+
+	for _, args := range [][]string{nil, {"accounts"}, {"accounts", "delete", "1"}, {"transfer", "create"}, {"freeze", "1"}} {
+		if _, _, err := a.lookup(args); !errors.Is(err, errUsage) {
+			t.Errorf("lookup(%q): got %v, want errUsage", args, err)
+		}
+	}
+}
+
+func TestRunRequiresAStaffActor(t *testing.T) {
+	for _, tc := range []struct {
+		actor string
+		role  auth.Role
+	}{
+		{"", auth.RoleSupport},
+		{"alice", auth.RoleCustomer},
+		{"alice", "root"},
+	} {
+		if err := run("table", tc.actor, tc.role, []string{"reconcile"}); err == nil {
+			t.Errorf("run as %q with role %q: want an error", tc.actor, tc.role)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"simple_bank/server/internal/models"
+)
+
+// column is a field shown for each row of a listing
+type column[T any] struct {
+	name  string
+	value func(T) any
+}
+
+var accountColumns = []column[models.Account]{
+	{"id", func(a models.Account) any { return a.ID }},
+	{"owner", func(a models.Account) any { return a.Owner }},
+	{"type", func(a models.Account) any { return a.Type }},
+	{"currency", func(a models.Account) any { return a.Currency }},
+	{"balance", func(a models.Account) any { return a.Balance }},
+	{"status", func(a models.Account) any { return a.Status }},
+	{"created_at", func(a models.Account) any { return a.CreatedAt }},
+}
+
+var transferColumns = []column[models.Transfer]{
+	{"id", func(t models.Transfer) any { return t.ID }},
+	{"from_account_id", func(t models.Transfer) any { return t.FromAccountID }},
+	{"to_account_id", func(t models.Transfer) any { return t.ToAccountID }},
+	{"amount", func(t models.Transfer) any { return t.Amount }},
+	{"reversal_of", func(t models.Transfer) any { return t.ReversalOf }},
+	{"created_at", func(t models.Transfer) any { return t.CreatedAt }},
+}
+
+var entryColumns = []column[models.Entry]{
+	{"id", func(e models.Entry) any { return e.ID }},
+	{"account_id", func(e models.Entry) any { return e.AccountID }},
+	{"amount", func(e models.Entry) any { return e.Amount }},
+	{"transfer_id", func(e models.Entry) any { return e.TransferID }},
+	{"created_at", func(e models.Entry) any { return e.CreatedAt }},
+}
+
+// statementColumns add the running balance to entries
+var statementColumns = []column[models.Entry]{
+	{"id", func(e models.Entry) any { return e.ID }},
+	{"created_at", func(e models.Entry) any { return e.CreatedAt }},
+	{"amount", func(e models.Entry) any { return e.Amount }},
+	{"balance", func(e models.Entry) any { return e.RunningBalance }},
+	{"transfer_id", func(e models.Entry) any { return e.TransferID }},
+}
+
+// printer writes command results as an aligned table or as JSON
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+// printRows writes rows as a table with a header, or as a JSON array
+func printRows[T any](p printer, columns []column[T], rows []T) error {
+	if p.json {
+		records := make([]map[string]any, len(rows))
+		for i, row := range rows {
+			records[i] = record(columns, row)
+		}
+		return p.encode(records)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(c.name)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(cells(columns, row), "\t"))
+	}
+	return tw.Flush()
+}
+
+// printRow writes a single row as a table, or as a JSON object
+func printRow[T any](p printer, columns []column[T], row T) error {
+	if p.json {
+		return p.encode(record(columns, row))
+	}
+	return printRows(p, columns, []T{row})
+}
+
+func (p printer) encode(v any) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// record maps column names to the row's values
+func record[T any](columns []column[T], row T) map[string]any {
+	values := make(map[string]any, len(columns))
+	for _, c := range columns {
+		values[c.name] = c.value(row)
+	}
+	return values
+}
+
+// cells formats the row's values as text
+func cells[T any](columns []column[T], row T) []string {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = cell(c.value(row))
+	}
+	return values
+}
+
+func cell(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case *int64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+func (a *app) createTransfer(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("transfers create", flag.ContinueOnError)
+	from := flags.Int64("from", 0, "account sending the money")
+	to := flags.Int64("to", 0, "account receiving the money")
+	amount := flags.Int64("amount", 0, "amount in minor units")
+	if err := parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	transfer, err := a.services.Transfer.CreateTransfer(ctx, *from, *to, *amount)
+	if err != nil {
+		return err
+	}
+	return printRow(a.out, transferColumns, *transfer)
+}
+
+func (a *app) reverseTransfer(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("transfers reverse", flag.ContinueOnError)
+	reason := flags.String("reason", "", "why, recorded in the admin action log")
+	if err := parse(flags, args, 1); err != nil {
+		return err
+	}
+	id, err := parseID(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	reversal, err := a.services.Admin.ReverseTransfer(ctx, id, *reason)
+	if err != nil {
+		return err
+	}
+	return printRow(a.out, transferColumns, *reversal)
+}
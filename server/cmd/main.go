@@ -2,74 +2,271 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"simple_bank/server/config"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/changefeed"
 	"simple_bank/server/internal/database"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/grpcapi"
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/metrics"
+	"simple_bank/server/internal/models"
 	"simple_bank/server/internal/repositories"
 	"simple_bank/server/internal/routes"
 	service "simple_bank/server/internal/services"
+	"simple_bank/server/internal/stream"
+	"simple_bank/server/internal/tracing"
+	"simple_bank/server/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Load configuration; flags come before any subcommand
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatal("Failed to load config:", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Everything logs JSON through slog, including the standard logger
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevels, []byte(cfg.LogPseudonymKey)))
+
+	if len(args) > 0 {
+		if err := runCommand(cfg, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := cfg.ValidateServer(); err != nil {
+		fatal("Invalid configuration", err)
+	}
+	gin.SetMode(cfg.GinMode)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, cfg.TracingSampleRatio)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	if cfg.DBAutoMigrate {
+		if err := migrateUp(cfg); err != nil {
+			fatal("Failed to migrate database", err)
+		}
 	}
 
 	// Connect to database
 	err = database.ConnectDB(cfg)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
 	}
 
-	// Initialize repositories and services
+	// Refuse to serve until the schema has every migration this build needs
 	db := database.GetDB()
-	repo := repositories.NewRepository(db)
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to get database handle", err)
+	}
+	checkSchema := health.Migrations(sqlDB, database.SchemaVersion)
+	if _, err := checkSchema(context.Background()); err != nil {
+		fatal("Database schema does not match this build; run `server migrate up`", err)
+	}
 
-	// Initialize services
-	services := service.NewServices(repo, db)
+	// Initialize repositories and services
+	timeouts := repositories.Timeouts{
+		Read:        cfg.DBReadTimeout,
+		Write:       cfg.DBWriteTimeout,
+		Transaction: cfg.DBTransactionTimeout,
+	}
+	repo := repositories.NewRepository(db, timeouts)
+	uow := repositories.NewUnitOfWork(db, timeouts)
+
+	// Initialize services; the broker fans live account updates out to streams
+	broker := stream.NewBroker()
+	services := service.NewServices(repo, uow, broker,
+		service.AccountTypePolicy{Multiple: cfg.AccountMultipleTypes},
+		service.WebhookPolicy{AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks},
+	)
+
+	// Start background workers: the outbox relay feeds webhook deliveries
+	// to the dispatcher, and the change feed pushes committed events to live
+	// streams on this replica and wakes the relay
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	relay := events.NewRelay(uow, cfg.OutboxBatchSize, cfg.OutboxPollInterval,
+		events.NewLogSink(),
+		webhooks.NewSink(repo.Webhook, repo.Delivery, services.Webhook),
+	)
+	dispatcher := webhooks.NewDispatcher(uow, webhooks.NewClient(cfg.WebhookTimeout, cfg.WebhookAllowPrivateNetworks),
+		cfg.WebhookMaxAttempts, cfg.WebhookBatchSize, cfg.WebhookPollInterval)
+	listener := changefeed.NewListener(changefeed.Postgres(database.DSN(cfg)), repo.Outbox,
+		broker,
+		events.NewFuncSink("relay", func(context.Context, []models.OutboxEvent) error {
+			relay.Wake()
+			return nil
+		}),
+	)
+	workers.Go(func() { relay.Run(workersCtx) })
+	workers.Go(func() { dispatcher.Run(workersCtx) })
+	workers.Go(func() { listener.Run(workersCtx) })
+
+	// Liveness watches the workers; readiness the database
+	checker := health.NewChecker(cfg.HealthCheckTimeout)
+	checker.AddLiveness("workers", health.Heartbeats(cfg.WorkerHeartbeatTimeout, map[string]*health.Heartbeat{
+		"relay":      relay.Heartbeat(),
+		"dispatcher": dispatcher.Heartbeat(),
+		"changefeed": listener.Heartbeat(),
+	}))
+	checker.AddReadiness("database", health.Database(sqlDB))
+	checker.AddReadiness("migrations", checkSchema)
+	checker.AddReadiness("pool", health.Pool(sqlDB, cfg.DBPoolMaxSaturation))
 
 	// Create HTTP server with Gin
-	router := routes.SetupRouter(services)
+	router := routes.SetupRouter(cfg, services, checker)
 
 	// Create server
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.AppPort),
 		Handler:      router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
 	}
 
-	// Start server in a goroutine
+	// Prometheus metrics have their own port, which is not exposed publicly
+	metricsServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.MetricsPort),
+		Handler:           metrics.Handler(),
+		ReadHeaderTimeout: cfg.HTTPReadTimeout,
+	}
+
+	// Create gRPC server on its own port, accepting the same credentials
+	grpcServer := grpcapi.NewServer(services,
+		auth.NewTokenAuthenticator([]byte(cfg.JWTSecret)),
+		auth.NewAPIKeyAuthenticator(services.APIKey),
+	)
+
+	// Start servers in goroutines
 	go func() {
-		log.Printf("Server starting on port %d", cfg.AppPort)
+		slog.Info("Server starting", "port", cfg.AppPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("Failed to start server", err)
 		}
 	}()
+	go func() {
+		slog.Info("Metrics server starting", "port", cfg.MetricsPort)
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Failed to start metrics server", err)
+		}
+	}()
+	if cfg.FeatureGRPC {
+		grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			fatal("Failed to listen on gRPC port", err)
+		}
+		go func() {
+			slog.Info("gRPC server starting", "port", cfg.GRPCPort)
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				fatal("Failed to start gRPC server", err)
+			}
+		}()
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+
+	// Fail readiness first and keep serving while load balancers notice
+	slog.Info("Draining before shutdown", "delay", cfg.ShutdownDrainDelay)
+	checker.Drain()
+	time.Sleep(cfg.ShutdownDrainDelay)
+	slog.Info("Shutting down server...")
 
 	// Give server time to finish existing requests
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		fatal("Server forced to shutdown", err)
+	}
+
+	// Let in-flight RPCs finish within the same deadline; open watch
+	// streams never finish on their own, so they are cut off after it.
+	// A server that never served stops at once.
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
+	// Stop the workers; undelivered events are picked up on next start
+	stopWorkers()
+	workers.Wait()
+
+	// Keep metrics scrapable until everything else has stopped
+	if err := metricsServer.Shutdown(ctx); err != nil {
+		slog.Error("Failed to stop metrics server", "error", err)
 	}
 
-	log.Println("Server exiting")
+	// Flush spans of the last requests
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Server exiting")
+}
+
+// runCommand runs a subcommand instead of the server
+func runCommand(cfg *config.Config, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "config":
+		// Print the effective configuration, for checking what a
+		// deployment resolves to without starting it
+		if len(args) != 2 || args[1] != "print" {
+			return errors.New("usage: server [flags] config print")
+		}
+		return cfg.Print(os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q; usage: server [flags] [migrate|config print]", args[0])
+	}
+}
+
+// migrateUp applies pending migrations before the server starts
+func migrateUp(cfg *config.Config) error {
+	migrator, err := database.NewMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	return migrator.Up()
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"simple_bank/server/config"
+	"simple_bank/server/internal/database"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+  up [N]       apply all pending migrations, or the next N
+  down [N]     revert the last N migrations (default 1)
+  status       show the applied and pending migrations
+  version      print the applied schema version
+  force V      mark version V as applied after fixing a failed migration`
+
+// runMigrate runs `server migrate`, managing the schema with the migrations
+// embedded in the binary
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+
+	migrator, err := database.NewMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch command {
+	case "up":
+		if len(args) == 0 {
+			err = migrator.Up()
+			break
+		}
+		n, parseErr := parsePositive(args[0])
+		if parseErr != nil {
+			return parseErr
+		}
+		err = migrator.Steps(n)
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			if n, err = parsePositive(args[0]); err != nil {
+				return err
+			}
+		}
+		err = migrator.Steps(-n)
+	case "force":
+		if len(args) == 0 {
+			return errors.New(migrateUsage)
+		}
+		version, parseErr := strconv.Atoi(args[0])
+		if parseErr != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		err = migrator.Force(version)
+	case "status":
+		return printMigrationStatus(migrator)
+	case "version":
+		version, dirty, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Println(version)
+		if dirty {
+			return fmt.Errorf("migration %d did not complete", version)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+	return printMigrationStatus(migrator)
+}
+
+// printMigrationStatus prints the applied version and lists the embedded
+// migrations that are still pending
+func printMigrationStatus(migrator *database.Migrator) error {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("applied: %d\nlatest:  %d\n", version, database.SchemaVersion)
+	if dirty {
+		fmt.Printf("migration %d did not complete; finish or undo it by hand, then record the resulting version with `server migrate force`\n", version)
+	}
+	for _, pending := range database.Migrations {
+		if pending > version {
+			fmt.Printf("pending: %d\n", pending)
+		}
+	}
+	return nil
+}
+
+func parsePositive(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid number of migrations %q", arg)
+	}
+	return n, nil
+}
@@ -1,10 +1,26 @@
+// Package config loads the server configuration. Each setting is read,
+// in increasing precedence, from its default, a YAML or TOML file, the
+// environment and command-line flags, and the result is validated as a
+// whole so that a typo fails startup instead of falling back to a default.
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"net/netip"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/tracing"
+
+	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
@@ -15,41 +31,297 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
-	AppPort    int
+	// DBMaxOpenConns and DBMaxIdleConns size the connection pool;
+	// connections are replaced after DBConnMaxLifetime
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	AppPort           int
+	// GRPCPort serves the gRPC API next to the REST API on AppPort
+	GRPCPort int
+	// MetricsPort serves Prometheus metrics. It is kept off AppPort so
+	// scrapers reach it from inside the network but API clients cannot.
+	MetricsPort int
+	// GinMode is debug, release or test
+	GinMode string
+	// TrustedProxies are the addresses and CIDR ranges of the proxies
+	// whose X-Forwarded-For headers are believed when resolving the
+	// client IP recorded in audit logs. None are trusted by default.
+	TrustedProxies []string
+	// HTTPReadTimeout, HTTPWriteTimeout and HTTPIdleTimeout bound reading
+	// a request, writing its response and keeping an idle connection
+	HTTPReadTimeout  time.Duration
+	HTTPWriteTimeout time.Duration
+	HTTPIdleTimeout  time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish once
+	// the server shuts down
+	ShutdownTimeout time.Duration
+	// JWTSecret is the HMAC key used to verify bearer tokens issued to customers
+	JWTSecret string
+	// OutboxBatchSize is how many events the relay delivers per transaction
+	OutboxBatchSize int
+	// OutboxPollInterval is how often the relay looks for new events
+	OutboxPollInterval time.Duration
+	// WebhookMaxAttempts is how many times a delivery is tried before it
+	// is dead-lettered
+	WebhookMaxAttempts int
+	// WebhookBatchSize is how many due deliveries the dispatcher claims at once
+	WebhookBatchSize int
+	// WebhookPollInterval is how often the dispatcher looks for due deliveries
+	WebhookPollInterval time.Duration
+	// WebhookTimeout bounds each delivery request
+	WebhookTimeout time.Duration
+	// WebhookAllowPrivateNetworks lets webhooks be sent to loopback,
+	// private and link-local addresses. Only enable it for local
+	// development: it lets subscribers reach internal services.
+	WebhookAllowPrivateNetworks bool
+	// LogLevels sets the minimum level of log records, overall and per
+	// component (http, grpc, gorm, changefeed, relay, webhooks)
+	LogLevels logging.Levels
+	// LogPseudonymKey is the HMAC key customer names in logs are replaced
+	// under. Replicas share it so their records can be correlated.
+	LogPseudonymKey string
+	// DBSlowQueryThreshold is how long a query may take before it is
+	// logged as slow
+	DBSlowQueryThreshold time.Duration
+	// DBReadTimeout and DBWriteTimeout bound each query and each insert,
+	// update or delete; DBTransactionTimeout bounds whole transactions
+	// such as transfers. Zero disables a timeout.
+	DBReadTimeout        time.Duration
+	DBWriteTimeout       time.Duration
+	DBTransactionTimeout time.Duration
+	// DBAutoMigrate applies pending migrations on start instead of
+	// requiring `server migrate up` to be run first
+	DBAutoMigrate bool
+	// HealthCheckTimeout bounds each check run by /livez and /readyz
+	HealthCheckTimeout time.Duration
+	// WorkerHeartbeatTimeout is how long a background worker may go
+	// without making progress before liveness fails
+	WorkerHeartbeatTimeout time.Duration
+	// DBPoolMaxSaturation is the fraction of pool connections in use at
+	// which readiness fails
+	DBPoolMaxSaturation float64
+	// ShutdownDrainDelay is how long the server keeps serving with
+	// readiness failing before it shuts down, so load balancers can stop
+	// sending it traffic
+	ShutdownDrainDelay time.Duration
+	// AccountMultipleTypes are the account types an owner may hold several
+	// accounts of in one currency
+	AccountMultipleTypes []string
+	// TracingExporter is where spans go: none, stdout or otlp. The OTLP
+	// collector is configured with the standard OTEL_EXPORTER_OTLP_*
+	// variables.
+	TracingExporter string
+	// TracingSampleRatio is the fraction of new traces that are recorded
+	TracingSampleRatio float64
+	// FeatureGRPC, FeatureGraphQL and FeatureAPIDocs turn the gRPC server,
+	// the GraphQL endpoint and the API description with Swagger UI on
+	FeatureGRPC    bool
+	FeatureGraphQL bool
+	FeatureAPIDocs bool
+
+	// logLevel and logLevelOverrides are parsed into LogLevels
+	logLevel          string
+	logLevelOverrides string
+	// sources records where each setting was last set, by key
+	sources map[string]string
 }
 
+// Sources a setting can come from, lowest precedence first
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// Default returns the configuration used when nothing is set. It does not
+// validate, since AUTH_JWT_SECRET and LOG_PSEUDONYM_KEY have no default.
+func Default() *Config {
+	cfg := &Config{sources: make(map[string]string)}
+	for _, s := range cfg.settings() {
+		if err := s.value.Set(s.def); err != nil {
+			panic(fmt.Sprintf("config: default of %s: %v", s.key, err))
+		}
+		cfg.sources[s.key] = sourceDefault
+	}
+	cfg.LogLevels, _ = logging.ParseLevels(cfg.logLevel, cfg.logLevelOverrides)
+	return cfg
+}
+
+// LoadConfig loads the configuration from defaults, the file named by
+// CONFIG_FILE and the environment
 func LoadConfig() (*Config, error) {
-	// Load .env file
-	err := godotenv.Load()
+	cfg, _, err := Load(nil)
+	return cfg, err
+}
+
+// Load loads the configuration from defaults, a file, the environment and
+// then the flags in args, the command-line arguments after the program
+// name. The file is named by the -config flag or CONFIG_FILE. Load returns
+// the arguments left after the flags, such as a subcommand.
+func Load(args []string) (*Config, []string, error) {
+	// Load .env file if there is one; it never overrides the environment
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("load .env: %w", err)
+	}
+
+	cfg := Default()
+	settings := cfg.settings()
+
+	// Flags are applied last but parsed first since they may name the file
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := flags.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration `file`")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		record := func(value string) error {
+			flagValues[s.key] = value
+			return nil
+		}
+		if _, ok := s.value.(boolValue); ok {
+			flags.BoolFunc(flagName(s.key), "overrides "+s.key, record)
+		} else {
+			flags.Func(flagName(s.key), "overrides "+s.key, record)
+		}
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	fileValues, err := readFile(*path, settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	envValues, err := readEnv(settings)
 	if err != nil {
-		fmt.Println("Warning: .env file not found")
+		return nil, nil, err
 	}
 
-	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "postgres"),
-		DBName:     getEnv("DB_NAME", "simple_bank"),
-		DBSSLMode:  getEnv("DB_SSL_MODE", "disable"),
-		AppPort:    getEnvAsInt("APP_PORT", 8080),
-	}, nil
+	var errs []error
+	for _, layer := range []struct {
+		source string
+		values map[string]string
+	}{
+		{sourceFile, fileValues},
+		{sourceEnv, envValues},
+		{sourceFlag, flagValues},
+	} {
+		for _, s := range settings {
+			value, ok := layer.values[s.key]
+			if !ok {
+				continue
+			}
+			if err := s.value.Set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s): %w", s.key, layer.source, err))
+			}
+			cfg.sources[s.key] = layer.source
+		}
+	}
+	if len(errs) == 0 {
+		errs = cfg.validate()
+	}
+	if len(errs) > 0 {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return cfg, flags.Args(), nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// validate checks the settings together, reporting every problem at once
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	var err error
+	c.LogLevels, err = logging.ParseLevels(c.logLevel, c.logLevelOverrides)
+	check(err == nil, "LOG_LEVEL or LOG_LEVELS: %v", err)
+
+	dbPort, err := strconv.Atoi(c.DBPort)
+	check(err == nil && validPort(dbPort), "DB_PORT must be between 1 and 65535")
+	check(validPort(c.AppPort), "APP_PORT must be between 1 and 65535")
+	check(validPort(c.GRPCPort), "GRPC_PORT must be between 1 and 65535")
+	check(validPort(c.MetricsPort), "METRICS_PORT must be between 1 and 65535")
+	check(c.MetricsPort != c.AppPort && c.MetricsPort != c.GRPCPort, "METRICS_PORT must differ from APP_PORT and GRPC_PORT")
+	check(slices.Contains([]string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}, c.DBSSLMode),
+		"DB_SSL_MODE %q is not a Postgres sslmode", c.DBSSLMode)
+	check(slices.Contains([]string{gin.DebugMode, gin.ReleaseMode, gin.TestMode}, c.GinMode),
+		"GIN_MODE must be debug, release or test")
+	for _, proxy := range c.TrustedProxies {
+		_, prefixErr := netip.ParsePrefix(proxy)
+		_, addrErr := netip.ParseAddr(proxy)
+		check(prefixErr == nil || addrErr == nil, "TRUSTED_PROXIES: %q is not an IP address or CIDR range", proxy)
+	}
+	check(slices.Contains([]string{tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP}, c.TracingExporter),
+		"TRACING_EXPORTER must be none, stdout or otlp")
+
+	check(c.DBMaxOpenConns > 0, "DB_MAX_OPEN_CONNS must be positive")
+	check(c.DBMaxIdleConns >= 0 && c.DBMaxIdleConns <= c.DBMaxOpenConns, "DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	check(c.OutboxBatchSize > 0, "OUTBOX_BATCH_SIZE must be positive")
+	check(c.WebhookMaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive")
+	check(c.WebhookBatchSize > 0, "WEBHOOK_BATCH_SIZE must be positive")
+	check(c.DBPoolMaxSaturation > 0 && c.DBPoolMaxSaturation <= 1, "DB_POOL_MAX_SATURATION must be above 0 and at most 1")
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	// Intervals drive tickers and timeouts drive deadlines, so they must be
+	// positive; zero disables the database timeouts
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"OUTBOX_POLL_INTERVAL", c.OutboxPollInterval},
+		{"WEBHOOK_POLL_INTERVAL", c.WebhookPollInterval},
+		{"WEBHOOK_TIMEOUT", c.WebhookTimeout},
+		{"HEALTH_CHECK_TIMEOUT", c.HealthCheckTimeout},
+		{"WORKER_HEARTBEAT_TIMEOUT", c.WorkerHeartbeatTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+	} {
+		check(d.value > 0, "%s must be positive", d.key)
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", c.DBConnMaxLifetime},
+		{"DB_SLOW_QUERY_THRESHOLD", c.DBSlowQueryThreshold},
+		{"DB_READ_TIMEOUT", c.DBReadTimeout},
+		{"DB_WRITE_TIMEOUT", c.DBWriteTimeout},
+		{"DB_TRANSACTION_TIMEOUT", c.DBTransactionTimeout},
+		{"HTTP_READ_TIMEOUT", c.HTTPReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTPIdleTimeout},
+		{"SHUTDOWN_DRAIN_DELAY", c.ShutdownDrainDelay},
+	} {
+		check(d.value >= 0, "%s cannot be negative", d.key)
+	}
+
+	for _, accountType := range c.AccountMultipleTypes {
+		check(models.ValidAccountType(accountType), "ACCOUNT_MULTIPLE_TYPES: unknown account type %q", accountType)
 	}
-	return defaultValue
+	return errs
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	valueStr := getEnv(key, "")
-	if valueStr == "" {
-		return defaultValue
+// ValidateServer checks what only serving the API needs, so that tools
+// such as `server migrate` run without it
+func (c *Config) ValidateServer() error {
+	// An empty HMAC key would let anyone mint valid tokens
+	if c.JWTSecret == "" {
+		return fmt.Errorf("AUTH_JWT_SECRET must be set")
 	}
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
+	// Without a shared key each process would pick its own
+	if c.LogPseudonymKey == "" {
+		return fmt.Errorf("LOG_PSEUDONYM_KEY must be set")
 	}
-	return defaultValue
+	return nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port < 65536
+}
+
+// flagName turns a key such as DB_HOST into the flag name db-host
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
 }
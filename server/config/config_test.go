@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simple_bank/server/config"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayersFileEnvAndFlags(t *testing.T) {
+	path := writeFile(t, "config.yaml", "app_port: 8081\ngrpc_port: 9091\ndb:\n  host: db.internal\n  max_open_conns: 20\n")
+	t.Setenv("GRPC_PORT", "9092")
+	t.Setenv("DB_MAX_OPEN_CONNS", "30")
+
+	cfg, args, err := config.Load([]string{"-config", path, "-db-max-open-conns", "40", "migrate", "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBName != "simple_bank" || cfg.AppPort != 8081 || cfg.DBHost != "db.internal" || cfg.GRPCPort != 9092 || cfg.DBMaxOpenConns != 40 {
+		t.Fatalf("unexpected configuration: %+v", cfg)
+	}
+	if strings.Join(args, " ") != "migrate up" {
+		t.Fatalf("args = %q", args)
+	}
+}
+
+func TestLoadReadsTOML(t *testing.T) {
+	path := writeFile(t, "config.toml", "feature_graphql = false\n[http]\nread_timeout = \"3s\"\n")
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.FeatureGraphQL || cfg.HTTPReadTimeout.Seconds() != 3 {
+		t.Fatalf("unexpected configuration: %+v", cfg)
+	}
+}
+
+func TestLoadRejectsInvalidSettings(t *testing.T) {
+	for name, setup := range map[string]func(t *testing.T){
+		"malformed int":   func(t *testing.T) { t.Setenv("APP_PORT", "abc") },
+		"out of range":    func(t *testing.T) { t.Setenv("GRPC_PORT", "70000") },
+		"idle above open": func(t *testing.T) { t.Setenv("DB_MAX_IDLE_CONNS", "500") },
+		"metrics on app":  func(t *testing.T) { t.Setenv("METRICS_PORT", "8080") },
+		"proxy":           func(t *testing.T) { t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy.internal") },
+		"unknown key":     func(t *testing.T) { t.Setenv("CONFIG_FILE", writeFile(t, "config.yaml", "bogus: 1\n")) },
+		"secret twice": func(t *testing.T) {
+			t.Setenv("DB_PASSWORD", "a")
+			t.Setenv("DB_PASSWORD_FILE", writeFile(t, "password", "b"))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			setup(t)
+			if _, _, err := config.Load(nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestPrintMasksSecretsFromFiles(t *testing.T) {
+	t.Setenv("AUTH_JWT_SECRET_FILE", writeFile(t, "secret", "hunter2\n"))
+
+	cfg, _, err := config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.JWTSecret != "hunter2" {
+		t.Fatalf("JWTSecret = %q", cfg.JWTSecret)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "hunter2") || !strings.Contains(buf.String(), "AUTH_JWT_SECRET") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
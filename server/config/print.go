@@ -0,0 +1,25 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// mask replaces secrets when printed
+const mask = "********"
+
+// Print writes every setting with its value and where it came from.
+// Secrets that are set are masked.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range c.settings() {
+		value := s.value.String()
+		if s.secret && value != "" {
+			value = mask
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, value, c.sources[s.key])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple_bank/server/internal/models"
+)
+
+// setting is one configuration value. key names it in the environment;
+// files use it in lower case and flags in lower case with dashes.
+type setting struct {
+	key   string
+	def   string
+	value flag.Value
+	// secret settings are masked when printed and can be read from the
+	// file named by key with a _FILE suffix
+	secret bool
+}
+
+// settings binds every setting to its field of c
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "APP_PORT", def: "8080", value: intValue{&c.AppPort}},
+		{key: "GRPC_PORT", def: "9090", value: intValue{&c.GRPCPort}},
+		{key: "METRICS_PORT", def: "9100", value: intValue{&c.MetricsPort}},
+		{key: "GIN_MODE", def: "release", value: stringValue{&c.GinMode}},
+		{key: "TRUSTED_PROXIES", value: listValue{&c.TrustedProxies}},
+		{key: "HTTP_READ_TIMEOUT", def: "15s", value: durationValue{&c.HTTPReadTimeout}},
+		{key: "HTTP_WRITE_TIMEOUT", def: "15s", value: durationValue{&c.HTTPWriteTimeout}},
+		{key: "HTTP_IDLE_TIMEOUT", def: "60s", value: durationValue{&c.HTTPIdleTimeout}},
+		{key: "SHUTDOWN_TIMEOUT", def: "10s", value: durationValue{&c.ShutdownTimeout}},
+		{key: "SHUTDOWN_DRAIN_DELAY", def: "5s", value: durationValue{&c.ShutdownDrainDelay}},
+		{key: "AUTH_JWT_SECRET", value: stringValue{&c.JWTSecret}, secret: true},
+
+		{key: "DB_HOST", def: "localhost", value: stringValue{&c.DBHost}},
+		{key: "DB_PORT", def: "5432", value: stringValue{&c.DBPort}},
+		{key: "DB_USER", def: "postgres", value: stringValue{&c.DBUser}},
+		{key: "DB_PASSWORD", def: "postgres", value: stringValue{&c.DBPassword}, secret: true},
+		{key: "DB_NAME", def: "simple_bank", value: stringValue{&c.DBName}},
+		{key: "DB_SSL_MODE", def: "disable", value: stringValue{&c.DBSSLMode}},
+		{key: "DB_MAX_OPEN_CONNS", def: "100", value: intValue{&c.DBMaxOpenConns}},
+		{key: "DB_MAX_IDLE_CONNS", def: "10", value: intValue{&c.DBMaxIdleConns}},
+		{key: "DB_CONN_MAX_LIFETIME", def: "1h", value: durationValue{&c.DBConnMaxLifetime}},
+		{key: "DB_POOL_MAX_SATURATION", def: "0.9", value: floatValue{&c.DBPoolMaxSaturation}},
+		{key: "DB_SLOW_QUERY_THRESHOLD", def: "200ms", value: durationValue{&c.DBSlowQueryThreshold}},
+		{key: "DB_READ_TIMEOUT", def: "5s", value: durationValue{&c.DBReadTimeout}},
+		{key: "DB_WRITE_TIMEOUT", def: "5s", value: durationValue{&c.DBWriteTimeout}},
+		{key: "DB_TRANSACTION_TIMEOUT", def: "10s", value: durationValue{&c.DBTransactionTimeout}},
+		{key: "DB_AUTO_MIGRATE", def: "false", value: boolValue{&c.DBAutoMigrate}},
+
+		{key: "OUTBOX_BATCH_SIZE", def: "100", value: intValue{&c.OutboxBatchSize}},
+		{key: "OUTBOX_POLL_INTERVAL", def: "1s", value: durationValue{&c.OutboxPollInterval}},
+		{key: "WEBHOOK_MAX_ATTEMPTS", def: "8", value: intValue{&c.WebhookMaxAttempts}},
+		{key: "WEBHOOK_BATCH_SIZE", def: "10", value: intValue{&c.WebhookBatchSize}},
+		{key: "WEBHOOK_POLL_INTERVAL", def: "2s", value: durationValue{&c.WebhookPollInterval}},
+		{key: "WEBHOOK_TIMEOUT", def: "10s", value: durationValue{&c.WebhookTimeout}},
+		{key: "WEBHOOK_ALLOW_PRIVATE_NETWORKS", def: "false", value: boolValue{&c.WebhookAllowPrivateNetworks}},
+
+		{key: "LOG_LEVEL", def: "info", value: stringValue{&c.logLevel}},
+		{key: "LOG_LEVELS", value: stringValue{&c.logLevelOverrides}},
+		{key: "LOG_PSEUDONYM_KEY", value: stringValue{&c.LogPseudonymKey}, secret: true},
+		{key: "HEALTH_CHECK_TIMEOUT", def: "2s", value: durationValue{&c.HealthCheckTimeout}},
+		{key: "WORKER_HEARTBEAT_TIMEOUT", def: "2m", value: durationValue{&c.WorkerHeartbeatTimeout}},
+		{key: "TRACING_EXPORTER", def: "none", value: stringValue{&c.TracingExporter}},
+		{key: "TRACING_SAMPLE_RATIO", def: "1", value: floatValue{&c.TracingSampleRatio}},
+
+		{key: "ACCOUNT_MULTIPLE_TYPES", def: models.AccountTypeSavings, value: listValue{&c.AccountMultipleTypes}},
+		{key: "FEATURE_GRPC", def: "true", value: boolValue{&c.FeatureGRPC}},
+		{key: "FEATURE_GRAPHQL", def: "true", value: boolValue{&c.FeatureGraphQL}},
+		{key: "FEATURE_API_DOCS", def: "true", value: boolValue{&c.FeatureAPIDocs}},
+	}
+}
+
+// The values below parse text into a field and format it back, like the
+// flag package's own values
+
+type stringValue struct{ p *string }
+
+func (v stringValue) Set(s string) error { *v.p = s; return nil }
+func (v stringValue) String() string     { return *v.p }
+
+type intValue struct{ p *int }
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v.p = n
+	return nil
+}
+
+func (v intValue) String() string { return strconv.Itoa(*v.p) }
+
+type floatValue struct{ p *float64 }
+
+func (v floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v.p = f
+	return nil
+}
+
+func (v floatValue) String() string { return strconv.FormatFloat(*v.p, 'g', -1, 64) }
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", s)
+	}
+	*v.p = b
+	return nil
+}
+
+func (v boolValue) String() string { return strconv.FormatBool(*v.p) }
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 5s or 1m30s", s)
+	}
+	*v.p = d
+	return nil
+}
+
+func (v durationValue) String() string { return v.p.String() }
+
+// listValue is a comma-separated list; empty items are dropped
+type listValue struct{ p *[]string }
+
+func (v listValue) Set(s string) error {
+	var list []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*v.p = list
+	return nil
+}
+
+func (v listValue) String() string { return strings.Join(*v.p, ",") }
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// secretFileSuffix names the file a secret is read from, as in
+// DB_PASSWORD_FILE=/run/secrets/db_password
+const secretFileSuffix = "_FILE"
+
+// readFile reads the settings in a YAML or TOML file, chosen by its
+// extension. Keys are setting keys in any case, and nested tables are
+// joined with underscores, so `db: {host: x}` sets DB_HOST. An empty path
+// reads nothing.
+func readFile(path string, settings []setting) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format %q, want .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", doc, values)
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+		if s.secret {
+			known[s.key+secretFileSuffix] = true
+		}
+	}
+	for key := range values {
+		if !known[key] {
+			return nil, fmt.Errorf("config file %s: unknown setting %q", path, strings.ToLower(key))
+		}
+	}
+	return values, readSecretFiles(values, settings)
+}
+
+// flatten collects the values of doc under upper-case keys joined with
+// underscores. Lists become comma-separated.
+func flatten(prefix string, doc map[string]any, values map[string]string) {
+	for key, value := range doc {
+		key = strings.ToUpper(key)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// readEnv reads the settings set in the environment. Empty variables are
+// ignored, as if unset.
+func readEnv(settings []setting) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, s := range settings {
+		keys := []string{s.key}
+		if s.secret {
+			keys = append(keys, s.key+secretFileSuffix)
+		}
+		for _, key := range keys {
+			if value := os.Getenv(key); value != "" {
+				values[key] = value
+			}
+		}
+	}
+	return values, readSecretFiles(values, settings)
+}
+
+// readSecretFiles replaces the _FILE entry of each secret in values with
+// the contents of the file it names, less trailing newlines
+func readSecretFiles(values map[string]string, settings []setting) error {
+	for _, s := range settings {
+		path, ok := values[s.key+secretFileSuffix]
+		if !s.secret || !ok {
+			continue
+		}
+		if _, ok := values[s.key]; ok {
+			return fmt.Errorf("set %s or %s, not both", s.key, s.key+secretFileSuffix)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.key+secretFileSuffix, err)
+		}
+		values[s.key] = strings.TrimRight(string(data), "\r\n")
+		delete(values, s.key+secretFileSuffix)
+	}
+	return nil
+}
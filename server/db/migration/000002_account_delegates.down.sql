@@ -0,0 +1 @@
+DROP TABLE IF EXISTS account_delegates;
\ No newline at end of file
@@ -0,0 +1,15 @@
+CREATE TABLE "account_delegates" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "delegate" varchar NOT NULL,
+  "can_transfer" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "account_delegates" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX ON "account_delegates" ("account_id", "delegate");
+
+CREATE INDEX ON "account_delegates" ("delegate");
+
+COMMENT ON COLUMN "account_delegates"."can_transfer" IS 'view-only when false';
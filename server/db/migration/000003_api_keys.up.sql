@@ -0,0 +1,18 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "prefix" varchar NOT NULL,
+  "key_hash" varchar NOT NULL,
+  "scopes" text[] NOT NULL,
+  "account_ids" bigint[] NOT NULL DEFAULT '{}',
+  "created_by" varchar NOT NULL,
+  "expires_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "api_keys" ("prefix");
+
+COMMENT ON COLUMN "api_keys"."key_hash" IS 'hex SHA-256 of the full key';
+
+COMMENT ON COLUMN "api_keys"."account_ids" IS 'empty means any account';
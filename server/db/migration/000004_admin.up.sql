@@ -0,0 +1,20 @@
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+CREATE TABLE "admin_actions" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "role" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target_type" varchar NOT NULL,
+  "target_id" bigint NOT NULL,
+  "reason" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "admin_actions" ("actor");
+
+CREATE INDEX ON "admin_actions" ("target_type", "target_id");
+
+COMMENT ON COLUMN "accounts"."status" IS 'active or frozen';
+
+COMMENT ON COLUMN "admin_actions"."target_id" IS 'zero for actions without a single target';
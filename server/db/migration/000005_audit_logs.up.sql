@@ -0,0 +1,39 @@
+CREATE TABLE "audit_logs" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target_type" varchar NOT NULL,
+  "target_id" bigint NOT NULL,
+  "before" jsonb,
+  "after" jsonb,
+  "request_id" varchar NOT NULL DEFAULT '',
+  "source_ip" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_logs" ("actor");
+
+CREATE INDEX ON "audit_logs" ("target_type", "target_id");
+
+CREATE INDEX ON "audit_logs" ("created_at");
+
+-- The audit log is append-only: reject any attempt to rewrite history
+CREATE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_logs is append-only: % is not allowed', TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_logs_no_update_delete
+  BEFORE UPDATE OR DELETE ON "audit_logs"
+  FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+CREATE TRIGGER audit_logs_no_truncate
+  BEFORE TRUNCATE ON "audit_logs"
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
+
+REVOKE UPDATE, DELETE, TRUNCATE ON "audit_logs" FROM PUBLIC;
+
+COMMENT ON COLUMN "audit_logs"."before" IS 'null for creations';
+
+COMMENT ON COLUMN "audit_logs"."after" IS 'null for deletions';
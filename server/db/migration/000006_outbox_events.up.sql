@@ -0,0 +1,15 @@
+CREATE TABLE "outbox_events" (
+  "id" bigserial PRIMARY KEY,
+  "event_type" varchar NOT NULL,
+  "aggregate_type" varchar NOT NULL,
+  "aggregate_id" bigint NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "published_at" timestamptz
+);
+
+CREATE INDEX ON "outbox_events" ("id") WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox_events" ("aggregate_type", "aggregate_id");
+
+COMMENT ON COLUMN "outbox_events"."published_at" IS 'null until delivered to every sink';
@@ -0,0 +1,44 @@
+CREATE TABLE "webhook_subscriptions" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "account_id" bigint NOT NULL,
+  "url" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "event_types" text[] NOT NULL,
+  "balance_threshold" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "subscription_id" bigint NOT NULL,
+  "outbox_event_id" bigint NOT NULL,
+  "event_type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_subscriptions" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("subscription_id") REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("outbox_event_id") REFERENCES "outbox_events" ("id");
+
+CREATE INDEX ON "webhook_subscriptions" ("owner");
+
+CREATE INDEX ON "webhook_subscriptions" ("account_id");
+
+CREATE UNIQUE INDEX ON "webhook_deliveries" ("subscription_id", "outbox_event_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+CREATE INDEX ON "webhook_deliveries" ("status");
+
+COMMENT ON COLUMN "webhook_subscriptions"."secret" IS 'HMAC key for delivery signatures';
+
+COMMENT ON COLUMN "webhook_deliveries"."status" IS 'pending, delivered or dead';
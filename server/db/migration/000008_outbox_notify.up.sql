@@ -0,0 +1,18 @@
+-- Announce every outbox event to listeners once its transaction commits.
+-- The payload only identifies the event; listeners read it from the table
+-- since NOTIFY payloads are limited to 8000 bytes.
+CREATE FUNCTION outbox_events_notify() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('bank_events', json_build_object(
+    'id', NEW.id,
+    'event_type', NEW.event_type,
+    'aggregate_type', NEW.aggregate_type,
+    'aggregate_id', NEW.aggregate_id
+  )::text);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_events_notify
+  AFTER INSERT ON "outbox_events"
+  FOR EACH ROW EXECUTE FUNCTION outbox_events_notify();
@@ -0,0 +1,15 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+-- Link entries written before this migration through the events that recorded them
+UPDATE "entries" e
+SET "transfer_id" = (o."payload"->>'transfer_id')::bigint
+FROM "outbox_events" o
+WHERE o."event_type" = 'BalanceChanged'
+  AND o."payload" ? 'transfer_id'
+  AND (o."payload"->>'entry_id')::bigint = e."id";
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'null for entries not made by a transfer';
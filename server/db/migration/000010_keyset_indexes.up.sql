@@ -0,0 +1,15 @@
+-- Listings page newest first on (created_at, id); these indexes let a page
+-- continue from a cursor without scanning the rows before it
+CREATE INDEX "accounts_created_at_id_idx" ON "accounts" ("created_at" DESC, "id" DESC);
+
+CREATE INDEX "accounts_owner_created_at_id_idx" ON "accounts" ("owner", "created_at" DESC, "id" DESC);
+
+CREATE INDEX "entries_created_at_id_idx" ON "entries" ("created_at" DESC, "id" DESC);
+
+CREATE INDEX "entries_account_id_created_at_id_idx" ON "entries" ("account_id", "created_at" DESC, "id" DESC);
+
+CREATE INDEX "transfers_created_at_id_idx" ON "transfers" ("created_at" DESC, "id" DESC);
+
+CREATE INDEX "transfers_from_account_id_created_at_id_idx" ON "transfers" ("from_account_id", "created_at" DESC, "id" DESC);
+
+CREATE INDEX "transfers_to_account_id_created_at_id_idx" ON "transfers" ("to_account_id", "created_at" DESC, "id" DESC);
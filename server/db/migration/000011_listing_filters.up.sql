@@ -0,0 +1,11 @@
+-- Entry and transfer listings can be sorted by amount and filtered by
+-- counterparty or currency
+CREATE INDEX "entries_account_id_amount_id_idx" ON "entries" ("account_id", "amount" DESC, "id" DESC);
+
+CREATE INDEX "transfers_from_account_id_amount_id_idx" ON "transfers" ("from_account_id", "amount" DESC, "id" DESC);
+
+CREATE INDEX "transfers_to_account_id_amount_id_idx" ON "transfers" ("to_account_id", "amount" DESC, "id" DESC);
+
+CREATE INDEX "transfers_to_account_id_from_account_id_idx" ON "transfers" ("to_account_id", "from_account_id");
+
+CREATE INDEX "accounts_currency_idx" ON "accounts" ("currency");
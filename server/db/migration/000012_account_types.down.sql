@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "accounts_owner_currency_type_key";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "multiple";
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "type";
@@ -0,0 +1,14 @@
+-- Accounts have a type, and an owner may hold one account of each type per
+-- currency unless the type allowed several when the account was opened
+ALTER TABLE "accounts" ADD COLUMN "type" varchar NOT NULL DEFAULT 'checking';
+
+ALTER TABLE "accounts" ADD COLUMN "multiple" boolean NOT NULL DEFAULT false;
+
+-- Duplicates opened by racing requests before the constraint existed are
+-- kept, but only the oldest counts towards it
+UPDATE "accounts" SET "multiple" = true
+WHERE "id" NOT IN (
+  SELECT min("id") FROM "accounts" GROUP BY "owner", "currency", "type"
+);
+
+CREATE UNIQUE INDEX "accounts_owner_currency_type_key" ON "accounts" ("owner", "currency", "type") WHERE NOT "multiple";
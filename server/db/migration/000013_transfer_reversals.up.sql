@@ -0,0 +1,8 @@
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+-- A transfer is reversed at most once
+ALTER TABLE "transfers" ADD CONSTRAINT "transfers_reversal_of_key" UNIQUE ("reversal_of");
+
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'the transfer this one sent back, null for ordinary transfers';
@@ -0,0 +1,2 @@
+-- The links cannot be told apart from those made when the entries were
+-- written, and 000009's down migration drops the column with them
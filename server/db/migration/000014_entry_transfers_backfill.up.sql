@@ -0,0 +1,36 @@
+-- Link the entries 000009 could not, because no outbox event recorded them:
+-- those written before the outbox existed, or whose events were pruned. A
+-- transfer wrote its two entries in the same transaction just after itself,
+-- so an entry may belong to it when it moved the transfer's amount on one
+-- of its accounts within a second of it. A transfer is linked only when
+-- both its debit and its credit fit it and no other transfer, and no other
+-- entry fits either side; anything else is left unlinked rather than
+-- guessed, as a half-linked transfer would fail reconciliation.
+WITH "candidates" AS (
+  SELECT e."id" AS "entry_id", t."id" AS "transfer_id", e."amount" > 0 AS "credit"
+  FROM "entries" e
+  JOIN "transfers" t
+    ON (e."account_id" = t."from_account_id" AND e."amount" = -t."amount")
+    OR (e."account_id" = t."to_account_id" AND e."amount" = t."amount")
+  WHERE e."transfer_id" IS NULL
+    AND e."created_at" >= t."created_at"
+    AND e."created_at" < t."created_at" + interval '1 second'
+    AND NOT EXISTS (SELECT 1 FROM "entries" l WHERE l."transfer_id" = t."id")
+), "counted" AS (
+  SELECT *,
+    count(*) OVER (PARTITION BY "entry_id") AS "transfers",
+    count(*) OVER (PARTITION BY "transfer_id", "credit") AS "entries",
+    count(*) OVER (PARTITION BY "transfer_id") AS "sides"
+  FROM "candidates"
+), "pairs" AS (
+  SELECT "transfer_id"
+  FROM "counted"
+  WHERE "transfers" = 1 AND "entries" = 1 AND "sides" = 2
+  GROUP BY "transfer_id"
+  HAVING bool_or("credit") AND bool_or(NOT "credit") AND count(*) = 2
+)
+UPDATE "entries" e
+SET "transfer_id" = c."transfer_id"
+FROM "counted" c
+JOIN "pairs" p ON p."transfer_id" = c."transfer_id"
+WHERE c."entry_id" = e."id";
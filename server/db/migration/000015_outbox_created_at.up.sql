@@ -0,0 +1,2 @@
+-- The change feed looks back from a time after reconnecting
+CREATE INDEX ON "outbox_events" ("created_at");
@@ -0,0 +1,10 @@
+// Package migration embeds the SQL migrations so the server binary can
+// apply them itself with `server migrate`.
+package migration
+
+import "embed"
+
+// FS holds the numbered .up.sql and .down.sql files of this directory
+//
+//go:embed *.sql
+var FS embed.FS
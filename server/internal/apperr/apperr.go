@@ -0,0 +1,113 @@
+// Package apperr defines the errors the bank reports to API clients. Each
+// carries a stable Code that the HTTP, gRPC and GraphQL APIs translate
+// into their own status codes; any other error is an internal failure
+// whose details are not shown to clients.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code identifies a kind of failure. Codes are part of the API, so clients
+// may rely on them where messages may change.
+type Code string
+
+const (
+	// CodeInvalidRequest is a request the server cannot parse, such as a
+	// malformed body, parameter or page cursor
+	CodeInvalidRequest Code = "INVALID_REQUEST"
+	// CodeValidationFailed is a well-formed request whose values are not
+	// acceptable
+	CodeValidationFailed Code = "VALIDATION_FAILED"
+	CodeUnauthenticated  Code = "UNAUTHENTICATED"
+	CodeForbidden        Code = "FORBIDDEN"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeAccountNotFound  Code = "ACCOUNT_NOT_FOUND"
+	CodeTransferNotFound Code = "TRANSFER_NOT_FOUND"
+	CodeEntryNotFound    Code = "ENTRY_NOT_FOUND"
+	CodeWebhookNotFound  Code = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound Code = "DELIVERY_NOT_FOUND"
+	CodeAPIKeyNotFound   Code = "API_KEY_NOT_FOUND"
+	// CodeConflict is a request that clashes with existing data
+	CodeConflict          Code = "CONFLICT"
+	CodeAccountFrozen     Code = "ACCOUNT_FROZEN"
+	CodeInsufficientFunds Code = "INSUFFICIENT_FUNDS"
+	CodeCurrencyMismatch  Code = "CURRENCY_MISMATCH"
+	// CodeResumeExpired is a stream resumed from an event too far back to
+	// replay; the client reloads and subscribes afresh
+	CodeResumeExpired Code = "RESUME_EXPIRED"
+	CodeInternal      Code = "INTERNAL"
+)
+
+// statuses maps codes to HTTP status codes. Codes missing here are 500.
+var statuses = map[Code]int{
+	CodeInvalidRequest:    http.StatusBadRequest,
+	CodeValidationFailed:  http.StatusUnprocessableEntity,
+	CodeUnauthenticated:   http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeNotFound:          http.StatusNotFound,
+	CodeAccountNotFound:   http.StatusNotFound,
+	CodeTransferNotFound:  http.StatusNotFound,
+	CodeEntryNotFound:     http.StatusNotFound,
+	CodeWebhookNotFound:   http.StatusNotFound,
+	CodeDeliveryNotFound:  http.StatusNotFound,
+	CodeAPIKeyNotFound:    http.StatusNotFound,
+	CodeConflict:          http.StatusConflict,
+	CodeAccountFrozen:     http.StatusConflict,
+	CodeInsufficientFunds: http.StatusUnprocessableEntity,
+	CodeCurrencyMismatch:  http.StatusUnprocessableEntity,
+}
+
+// HTTPStatus returns the HTTP status code responses with code are sent with
+func (c Code) HTTPStatus() int {
+	if status, ok := statuses[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is a failure that can be reported to clients as is
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// New returns an error with code and a message clients can read
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf is New with a formatted message
+func Newf(code Code, format string, args ...any) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Invalid reports a request whose values are not acceptable
+func Invalid(message string) *Error {
+	return New(CodeValidationFailed, message)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions adds the code to GraphQL errors
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"code": e.Code}
+}
+
+// From returns the Error in err's chain, or an internal error hiding err
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return New(CodeInternal, "internal server error")
+}
+
+// CodeOf returns the code of the Error in err's chain, or CodeInternal
+func CodeOf(err error) Code {
+	return From(err).Code
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries API keys issued to service-to-service clients
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyVerifier resolves a raw API key to the principal it was issued for
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, rawKey string) (*Principal, error)
+}
+
+type apiKeyAuthenticator struct {
+	verifier APIKeyVerifier
+}
+
+// NewAPIKeyAuthenticator authenticates requests carrying an X-API-Key header
+func NewAPIKeyAuthenticator(verifier APIKeyVerifier) Authenticator {
+	return &apiKeyAuthenticator{verifier: verifier}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	rawKey := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if rawKey == "" {
+		return nil, ErrNoCredentials
+	}
+	return a.verifier.VerifyAPIKey(r.Context(), rawKey)
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"simple_bank/server/internal/apperr"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator when the request does
+	// not carry credentials it understands, so the next one can be tried
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrUnauthenticated is returned when credentials are missing or invalid
+	ErrUnauthenticated = apperr.New(apperr.CodeUnauthenticated, "authentication required")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject is the user name, matched against models.Account.Owner
+	Subject string
+	// Role decides which admin permissions the caller holds
+	Role Role
+	// APIKeyID is set when the caller authenticated with an API key
+	APIKeyID int64
+	// Scopes granted to an API key; users are not restricted by scope
+	Scopes []Scope
+	// AccountIDs restricts an API key to these accounts when not empty
+	AccountIDs []int64
+}
+
+// IsService reports whether the caller is a service using an API key
+func (p *Principal) IsService() bool {
+	return p.APIKeyID != 0
+}
+
+// Can reports whether the caller holds an admin permission. API keys
+// never do, whatever their scopes.
+func (p *Principal) Can(permission Permission) bool {
+	return !p.IsService() && p.Role.Can(permission)
+}
+
+// HasScope reports whether the caller was granted scope
+func (p *Principal) HasScope(scope Scope) bool {
+	if !p.IsService() {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessAccount reports whether an API key's allow-list includes the account
+func (p *Principal) CanAccessAccount(id int64) bool {
+	if len(p.AccountIDs) == 0 {
+		return true
+	}
+	for _, allowed := range p.AccountIDs {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator resolves the caller of an HTTP request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the principal stored in ctx, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,84 @@
+package auth
+
+// Role is the job function of a user, carried in the "role" token claim
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleSupport  Role = "support"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+// Permission is an operation on the admin API
+type Permission string
+
+const (
+	PermAccountsListAll  Permission = "accounts:list_all"
+	PermTransfersViewAny Permission = "transfers:view_any"
+	PermAccountsFreeze   Permission = "accounts:freeze"
+	PermBalancesAdjust   Permission = "balances:adjust"
+	PermAPIKeysManage    Permission = "api_keys:manage"
+	PermAdminActionsView Permission = "admin_actions:view"
+	PermAuditLogView     Permission = "audit_log:view"
+	PermWebhooksManage   Permission = "webhooks:manage"
+	// PermAccountsOperate lets staff act on any account as its owner
+	// could, such as opening accounts and sending transfers for customers
+	PermAccountsOperate  Permission = "accounts:operate"
+	PermTransfersReverse Permission = "transfers:reverse"
+	PermLedgerReconcile  Permission = "ledger:reconcile"
+	PermDataExport       Permission = "data:export"
+)
+
+// rolePermissions is the permission matrix. Customers hold no admin
+// permissions; each staff role extends the one below it.
+var rolePermissions = map[Role][]Permission{
+	RoleCustomer: {},
+	RoleSupport: {
+		PermAccountsListAll,
+		PermTransfersViewAny,
+	},
+	RoleOperator: {
+		PermAccountsListAll,
+		PermTransfersViewAny,
+		PermAccountsFreeze,
+		PermAuditLogView,
+		PermWebhooksManage,
+		PermLedgerReconcile,
+	},
+	RoleAdmin: {
+		PermAccountsListAll,
+		PermTransfersViewAny,
+		PermAccountsFreeze,
+		PermBalancesAdjust,
+		PermAPIKeysManage,
+		PermAdminActionsView,
+		PermAuditLogView,
+		PermWebhooksManage,
+		PermLedgerReconcile,
+		PermAccountsOperate,
+		PermTransfersReverse,
+		PermDataExport,
+	},
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// IsStaff reports whether r is any role other than customer
+func (r Role) IsStaff() bool {
+	return r.Valid() && r != RoleCustomer
+}
+
+// Can reports whether the permission matrix grants r the permission
+func (r Role) Can(permission Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package auth_test
+
+import (
+	"testing"
+
+	"simple_bank/server/internal/auth"
+)
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role       auth.Role
+		permission auth.Permission
+		want       bool
+	}{
+		{auth.RoleCustomer, auth.PermAccountsListAll, false},
+		{auth.RoleSupport, auth.PermAccountsListAll, true},
+		{auth.RoleSupport, auth.PermAccountsFreeze, false},
+		{auth.RoleOperator, auth.PermAccountsFreeze, true},
+		{auth.RoleOperator, auth.PermBalancesAdjust, false},
+		{auth.RoleAdmin, auth.PermBalancesAdjust, true},
+		{auth.RoleOperator, auth.PermLedgerReconcile, true},
+		{auth.RoleOperator, auth.PermAccountsOperate, false},
+		{auth.RoleAdmin, auth.PermAccountsOperate, true},
+		{auth.Role("root"), auth.PermAccountsListAll, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.role.Can(tt.permission); got != tt.want {
+			t.Errorf("%s.Can(%s) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeysHoldNoPermissions(t *testing.T) {
+	principal := &auth.Principal{Subject: "apikey:sbk_test", Role: auth.RoleAdmin, APIKeyID: 1}
+	if principal.Can(auth.PermAccountsListAll) {
+		t.Fatal("API key principal should not hold admin permissions")
+	}
+}
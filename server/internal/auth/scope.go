@@ -0,0 +1,29 @@
+package auth
+
+// Scope is a permission granted to an API key
+type Scope string
+
+const (
+	ScopeAccountsRead   Scope = "accounts:read"
+	ScopeAccountsWrite  Scope = "accounts:write"
+	ScopeTransfersRead  Scope = "transfers:read"
+	ScopeTransfersWrite Scope = "transfers:write"
+)
+
+// Scopes lists every scope an API key may be granted
+var Scopes = []Scope{
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransfersRead,
+	ScopeTransfersWrite,
+}
+
+// Valid reports whether s is a known scope
+func (s Scope) Valid() bool {
+	for _, scope := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
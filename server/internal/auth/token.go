@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type tokenAuthenticator struct {
+	secret []byte
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	// Role is set by the identity provider; users without one are customers
+	Role Role `json:"role,omitempty"`
+}
+
+// NewTokenAuthenticator verifies HS256 bearer tokens signed with secret.
+// The token subject becomes the principal.
+func NewTokenAuthenticator(secret []byte) Authenticator {
+	return &tokenAuthenticator{secret: secret}
+}
+
+func (a *tokenAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	raw, ok := bearerToken(r)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+
+	token, err := jwt.ParseWithClaims(raw, &tokenClaims{}, func(*jwt.Token) (any, error) {
+		return a.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	claims := token.Claims.(*tokenClaims)
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+
+	role := claims.Role
+	if role == "" {
+		role = RoleCustomer
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrUnauthenticated, role)
+	}
+
+	return &Principal{Subject: claims.Subject, Role: role}, nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
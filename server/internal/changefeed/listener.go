@@ -0,0 +1,295 @@
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/metrics"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Channel is the Postgres notification channel the outbox trigger uses
+const Channel = "bank_events"
+
+const (
+	backfillBatch = 500
+	minReconnect  = time.Second
+	maxReconnect  = 30 * time.Second
+	// keepalive bounds how long the listener waits for a notification
+	// before beating its heartbeat and waiting again
+	keepalive = 30 * time.Second
+	// lookBack is how far before its last read the listener looks again
+	// after reconnecting. A transaction holding a lower ID can commit after
+	// a higher one, but no later than it may run after writing the event,
+	// so this exceeds DB_TRANSACTION_TIMEOUT with room for clock skew.
+	lookBack = 2 * time.Minute
+)
+
+// Notification is the payload sent by the outbox_events_notify trigger
+type Notification struct {
+	ID            int64  `json:"id"`
+	EventType     string `json:"event_type"`
+	AggregateType string `json:"aggregate_type"`
+	AggregateID   int64  `json:"aggregate_id"`
+}
+
+// Conn is a connection listening on Channel
+type Conn interface {
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+// Dialer opens a Conn
+type Dialer func(ctx context.Context) (Conn, error)
+
+// Postgres returns a Dialer that connects to dsn and listens on Channel
+func Postgres(dsn string) Dialer {
+	return func(ctx context.Context) (Conn, error) {
+		conn, err := pgx.Connect(ctx, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("connect: %w", err)
+		}
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize()); err != nil {
+			_ = conn.Close(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("listen: %w", err)
+		}
+		return conn, nil
+	}
+}
+
+// Listener holds one LISTEN connection per server and distributes committed
+// outbox events to in-process subscribers such as SSE streams, the webhook
+// relay or caches. Every replica runs its own, so subscribers see changes
+// made through any replica. After reconnecting it backfills the events it
+// missed from the outbox table.
+type Listener struct {
+	dial   Dialer
+	outbox repositories.OutboxRepository
+	sinks  []events.Sink
+	// startID is where the listener started; events up to it are history
+	// it does not replay. lastID is the highest event ID delivered, and
+	// readAt when the outbox was last read up to it.
+	startID int64
+	lastID  int64
+	readAt  time.Time
+	// delivered holds the IDs delivered within lookBack with their
+	// creation times, so events read again are not delivered twice
+	delivered map[int64]time.Time
+	heartbeat health.Heartbeat
+	log       *slog.Logger
+}
+
+func NewListener(dial Dialer, outbox repositories.OutboxRepository, sinks ...events.Sink) *Listener {
+	return &Listener{
+		dial:      dial,
+		outbox:    outbox,
+		sinks:     sinks,
+		delivered: make(map[int64]time.Time),
+		log:       logging.For("changefeed"),
+	}
+}
+
+// Heartbeat beats while the listener waits for notifications or
+// reconnects, at least every keepalive
+func (l *Listener) Heartbeat() *health.Heartbeat {
+	return &l.heartbeat
+}
+
+// Run listens until ctx is cancelled, reconnecting with backoff
+func (l *Listener) Run(ctx context.Context) {
+	l.heartbeat.Beat()
+	if !l.position(ctx) {
+		return
+	}
+
+	wait := minReconnect
+	for {
+		connected, err := l.listen(ctx)
+		l.heartbeat.Beat()
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			wait = minReconnect
+		}
+		l.log.WarnContext(ctx, "connection lost", "error", err, "retry_in", wait)
+
+		if !sleep(ctx, wait) {
+			return
+		}
+		wait = min(wait*2, maxReconnect)
+	}
+}
+
+// position starts from the current end of the outbox rather than replaying
+// its history. It retries until the outbox can be read, since starting from
+// zero would redeliver every event ever written. It returns false if ctx is
+// cancelled first.
+func (l *Listener) position(ctx context.Context) bool {
+	for wait := minReconnect; ; wait = min(wait*2, maxReconnect) {
+		readAt := time.Now()
+		lastID, err := l.outbox.LastID()
+		l.heartbeat.Beat()
+		if err == nil {
+			l.startID, l.lastID, l.readAt = lastID, lastID, readAt
+			return true
+		}
+		l.log.WarnContext(ctx, "reading outbox position", "error", err, "retry_in", wait)
+		if !sleep(ctx, wait) {
+			return false
+		}
+	}
+}
+
+// sleep waits for d and reports whether ctx is still live
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// listen runs one connection until it fails. It reports whether LISTEN
+// succeeded so Run can reset its backoff.
+func (l *Listener) listen(ctx context.Context) (bool, error) {
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	// Catch up on anything committed while we were not listening
+	if err := l.catchUp(ctx); err != nil {
+		return true, err
+	}
+
+	for {
+		l.heartbeat.Beat()
+		// A timed out wait leaves the connection usable
+		waitCtx, cancel := context.WithTimeout(ctx, keepalive)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		if pgconn.Timeout(err) && ctx.Err() == nil {
+			continue
+		}
+		if err != nil {
+			return true, fmt.Errorf("wait: %w", err)
+		}
+
+		var n Notification
+		if err := json.Unmarshal([]byte(notification.Payload), &n); err != nil {
+			l.log.WarnContext(ctx, "ignoring malformed notification", "payload", notification.Payload)
+			continue
+		}
+
+		if n.ID > l.lastID {
+			err = l.backfill(ctx, l.lastID)
+		} else {
+			// A transaction holding a lower ID committed late
+			err = l.deliverOne(ctx, n.ID)
+		}
+		if err != nil {
+			return true, err
+		}
+	}
+}
+
+// catchUp delivers the events committed while the listener was not
+// listening. Reading only after lastID would miss lower IDs that committed
+// late, so it reads again from the first event created within lookBack of
+// the last read, skipping those already delivered.
+func (l *Listener) catchUp(ctx context.Context) error {
+	firstID, err := l.outbox.FirstIDSince(l.readAt.Add(-lookBack))
+	if err != nil {
+		return fmt.Errorf("backfill: %w", err)
+	}
+	after := l.lastID
+	if firstID > 0 && firstID <= after {
+		after = max(firstID-1, l.startID)
+	}
+	return l.backfill(ctx, after)
+}
+
+// backfill delivers the events after afterID that were not delivered yet
+func (l *Listener) backfill(ctx context.Context, afterID int64) error {
+	readAt := time.Now()
+	for {
+		batch, err := l.outbox.GetAfter(afterID, backfillBatch)
+		if err != nil {
+			return fmt.Errorf("backfill: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		l.deliver(ctx, batch)
+		afterID = batch[len(batch)-1].ID
+		l.lastID = max(l.lastID, afterID)
+		if len(batch) < backfillBatch {
+			break
+		}
+	}
+	l.readAt = readAt
+	l.forget()
+	return nil
+}
+
+func (l *Listener) deliverOne(ctx context.Context, id int64) error {
+	event, err := l.outbox.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load event %d: %w", id, err)
+	}
+	l.deliver(ctx, []models.OutboxEvent{*event})
+	return nil
+}
+
+// forget drops delivered IDs too old to be read again
+func (l *Listener) forget() {
+	horizon := l.readAt.Add(-2 * lookBack)
+	for id, createdAt := range l.delivered {
+		if createdAt.Before(horizon) {
+			delete(l.delivered, id)
+		}
+	}
+}
+
+// deliver hands the events not delivered before to every sink. Sinks are
+// best-effort here: a failing sink is logged and does not hold up the
+// others.
+func (l *Listener) deliver(ctx context.Context, batch []models.OutboxEvent) {
+	batch = slices.DeleteFunc(slices.Clone(batch), func(event models.OutboxEvent) bool {
+		_, seen := l.delivered[event.ID]
+		return seen
+	})
+	if len(batch) == 0 {
+		return
+	}
+	for _, event := range batch {
+		l.delivered[event.ID] = event.CreatedAt
+	}
+
+	metrics.ObserveLag("changefeed", batch[0].CreatedAt)
+	for _, sink := range l.sinks {
+		if err := sink.Deliver(ctx, batch); err != nil {
+			l.log.ErrorContext(ctx, "sink failed", "sink", sink.Name(), "error", err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package changefeed_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"simple_bank/server/internal/changefeed"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// fakeOutbox holds the committed events. LastID fails while lastIDErr is set.
+type fakeOutbox struct {
+	repositories.OutboxRepository
+	mu        sync.Mutex
+	events    []models.OutboxEvent
+	lastIDErr error
+}
+
+func (o *fakeOutbox) commit(ids ...int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, id := range ids {
+		o.events = append(o.events, models.OutboxEvent{ID: id, CreatedAt: time.Now()})
+	}
+	slices.SortFunc(o.events, func(a, b models.OutboxEvent) int { return int(a.ID - b.ID) })
+}
+
+func (o *fakeOutbox) LastID() (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.lastIDErr; err != nil {
+		o.lastIDErr = nil
+		return 0, err
+	}
+	if len(o.events) == 0 {
+		return 0, nil
+	}
+	return o.events[len(o.events)-1].ID, nil
+}
+
+func (o *fakeOutbox) GetAfter(afterID int64, limit int) ([]models.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var batch []models.OutboxEvent
+	for _, event := range o.events {
+		if event.ID > afterID && len(batch) < limit {
+			batch = append(batch, event)
+		}
+	}
+	return batch, nil
+}
+
+func (o *fakeOutbox) FirstIDSince(since time.Time) (int64, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var first int64
+	for _, event := range o.events {
+		if !event.CreatedAt.Before(since) && (first == 0 || event.ID < first) {
+			first = event.ID
+		}
+	}
+	return first, nil
+}
+
+func (o *fakeOutbox) GetByID(id int64) (*models.OutboxEvent, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, event := range o.events {
+		if event.ID == id {
+			return &event, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeConn receives the payloads sent on it; closing it drops the connection
+type fakeConn chan string
+
+func (c fakeConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case payload, ok := <-c:
+		if !ok {
+			return nil, errors.New("connection reset")
+		}
+		return &pgconn.Notification{Channel: changefeed.Channel, Payload: payload}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c fakeConn) Close(context.Context) error { return nil }
+
+func notify(id int64) string {
+	return `{"id":` + strconv.FormatInt(id, 10) + `}`
+}
+
+// listenerTest runs a listener on outbox and returns the connections it
+// dials, in order, and the IDs its sink receives
+func listenerTest(t *testing.T, outbox *fakeOutbox) (<-chan fakeConn, <-chan int64) {
+	t.Helper()
+	conns := make(chan fakeConn)
+	dial := func(ctx context.Context) (changefeed.Conn, error) {
+		conn := make(fakeConn)
+		select {
+		case conns <- conn:
+			return conn, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	delivered := make(chan int64, 100)
+	sink := events.NewFuncSink("recording", func(ctx context.Context, batch []models.OutboxEvent) error {
+		for _, event := range batch {
+			delivered <- event.ID
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		changefeed.NewListener(dial, outbox, sink).Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return conns, delivered
+}
+
+func receive(t *testing.T, delivered <-chan int64, want ...int64) {
+	t.Helper()
+	var got []int64
+	for range want {
+		select {
+		case id := <-delivered:
+			got = append(got, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("delivered %v, want %v", got, want)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+}
+
+func TestListenerStartsAtTheEndOfTheOutbox(t *testing.T) {
+	outbox := &fakeOutbox{lastIDErr: errors.New("connection refused")}
+	outbox.commit(1, 2, 3)
+	conns, delivered := listenerTest(t, outbox)
+
+	// The failed read is retried rather than replaying events 1 to 3
+	conn := <-conns
+	outbox.commit(4)
+	conn <- notify(4)
+	receive(t, delivered, 4)
+}
+
+func TestListenerBackfillsAfterReconnecting(t *testing.T) {
+	outbox := &fakeOutbox{}
+	conns, delivered := listenerTest(t, outbox)
+
+	conn := <-conns
+	outbox.commit(1)
+	conn <- notify(1)
+	receive(t, delivered, 1)
+	close(conn)
+
+	// Committed while disconnected, so never notified
+	outbox.commit(2, 3)
+	<-conns
+	receive(t, delivered, 2, 3)
+}
+
+func TestListenerDeliversLateLowerIDs(t *testing.T) {
+	outbox := &fakeOutbox{}
+	outbox.commit(1)
+	conns, delivered := listenerTest(t, outbox)
+	conn := <-conns
+
+	// 3 commits before 2
+	outbox.commit(3)
+	conn <- notify(3)
+	receive(t, delivered, 3)
+
+	conn <- "not json"
+	outbox.commit(2)
+	conn <- notify(2)
+	receive(t, delivered, 2)
+
+	// Still listening after the malformed payload
+	outbox.commit(4)
+	conn <- notify(4)
+	receive(t, delivered, 4)
+}
+
+func TestListenerBackfillsLateLowerIDsAfterReconnecting(t *testing.T) {
+	outbox := &fakeOutbox{}
+	outbox.commit(1)
+	conns, delivered := listenerTest(t, outbox)
+
+	conn := <-conns
+	outbox.commit(3)
+	conn <- notify(3)
+	receive(t, delivered, 3)
+	close(conn)
+
+	// 2 commits after 3 while disconnected, so it is never notified
+	outbox.commit(2)
+	conn = <-conns
+	receive(t, delivered, 2)
+
+	// Nothing is delivered twice
+	outbox.commit(4)
+	conn <- notify(3)
+	conn <- notify(4)
+	receive(t, delivered, 4)
+}
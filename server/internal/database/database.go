@@ -1,9 +1,14 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"simple_bank/server/config"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/metrics"
+	"simple_bank/server/internal/tracing"
+	"strings"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -13,35 +18,70 @@ import (
 
 var DB *gorm.DB
 
-func ConnectDB(config *config.Config) error {
-	dsn := fmt.Sprintf(
+// DSN builds the Postgres connection string from the configuration. Each
+// value is quoted, so a password may hold spaces, quotes or backslashes.
+func DSN(config *config.Config) string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
-		config.DBHost, config.DBUser, config.DBPassword,
-		config.DBName, config.DBPort, config.DBSSLMode,
+		quote(config.DBHost), quote(config.DBUser), quote(config.DBPassword),
+		quote(config.DBName), quote(config.DBPort), quote(config.DBSSLMode),
 	)
+}
+
+// quote writes value as a libpq keyword value
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, `'`, `\'`) + "'"
+}
 
+func ConnectDB(config *config.Config) error {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	DB, err = gorm.Open(postgres.Open(DSN(config)), &gorm.Config{
+		Logger: newLogger(config.DBSlowQueryThreshold),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return err
+	}
+
 	sqlDB, err := DB.DB()
 	if err != nil {
 		return err
 	}
 
 	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(config.DBMaxIdleConns)
+	sqlDB.SetMaxOpenConns(config.DBMaxOpenConns)
+	sqlDB.SetConnMaxLifetime(config.DBConnMaxLifetime)
 
-	log.Println("Database connected successfully")
+	if err := metrics.RegisterDB(sqlDB, config.DBName); err != nil {
+		return err
+	}
+
+	slog.Info("Database connected successfully")
 	return nil
 }
 
+// newLogger logs failed and slow queries, and every query when the gorm
+// component is at debug level. Query parameters are never logged since they
+// hold customer data.
+func newLogger(slowThreshold time.Duration) logger.Interface {
+	log := logging.For("gorm")
+	level := logger.Warn
+	if log.Enabled(context.Background(), slog.LevelDebug) {
+		level = logger.Info
+	}
+	return logger.NewSlogLogger(log, logger.Config{
+		LogLevel:                  level,
+		SlowThreshold:             slowThreshold,
+		ParameterizedQueries:      true,
+		IgnoreRecordNotFoundError: true,
+	})
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
@@ -0,0 +1,24 @@
+package database_test
+
+import (
+	"testing"
+
+	"simple_bank/server/config"
+	"simple_bank/server/internal/database"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestDSNQuotesValues(t *testing.T) {
+	cfg := config.Default()
+	cfg.DBPassword = `p@ss word' sslmode=disable \`
+	cfg.DBName = "bank's"
+
+	parsed, err := pgconn.ParseConfig(database.DSN(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Password != cfg.DBPassword || parsed.Database != cfg.DBName {
+		t.Errorf("got password %q and database %q, want %q and %q", parsed.Password, parsed.Database, cfg.DBPassword, cfg.DBName)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"simple_bank/server/config"
+	"simple_bank/server/db/migration"
+	"simple_bank/server/internal/logging"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Migrations are the versions of the embedded migrations, in order
+var Migrations = migrationVersions(migration.FS)
+
+// SchemaVersion is the latest embedded migration, which the server needs
+var SchemaVersion = Migrations[len(Migrations)-1]
+
+// migrationVersions lists the versions of the .up.sql files in fsys. The
+// files are embedded at build time, so a misnamed one is a build mistake.
+func migrationVersions(fsys fs.FS) []uint {
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil || len(names) == 0 {
+		panic("database: no migrations embedded")
+	}
+
+	versions := make([]uint, 0, len(names))
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 0)
+		if err != nil {
+			panic(fmt.Sprintf("database: migration %s is not numbered", name))
+		}
+		versions = append(versions, uint(version))
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// Migrator applies the embedded migrations over its own connection, so it
+// can run before the server connects or from `server migrate`. Concurrent
+// migrators wait on a Postgres advisory lock.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// NewMigrator connects to the configured database. Close it when done.
+func NewMigrator(cfg *config.Config) (*Migrator, error) {
+	db, err := sql.Open("pgx", DSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	driver, err := pgx.WithInstance(db, &pgx.Config{})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	source, err := iofs.New(migration.FS, ".")
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", source, cfg.DBName, driver)
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	m.Log = migrateLogger{logging.For("migrate")}
+	return &Migrator{m: m}, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Steps applies n pending migrations, or reverts n applied ones when n is
+// negative
+func (m *Migrator) Steps(n int) error {
+	return ignoreNoChange(m.m.Steps(n))
+}
+
+// Force records version as applied and clean without running anything, to
+// recover after a migration failed halfway and was fixed by hand
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Version returns the applied version, zero when nothing is, and whether
+// the last migration failed halfway
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Close releases the migrator's connection
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// migrateLogger routes the migrate library's messages through slog. Each
+// applied migration is logged; buffering and scheduling only at debug level.
+type migrateLogger struct {
+	log *slog.Logger
+}
+
+func (l migrateLogger) Printf(format string, v ...any) {
+	l.log.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l migrateLogger) Verbose() bool {
+	return l.log.Enabled(context.Background(), slog.LevelDebug)
+}
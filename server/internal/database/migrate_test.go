@@ -0,0 +1,26 @@
+package database_test
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"simple_bank/server/db/migration"
+	"simple_bank/server/internal/database"
+)
+
+func TestEmbeddedMigrationsAreComplete(t *testing.T) {
+	if database.SchemaVersion != database.Migrations[len(database.Migrations)-1] {
+		t.Fatalf("SchemaVersion = %d, want the latest migration %d", database.SchemaVersion, database.Migrations[len(database.Migrations)-1])
+	}
+
+	for i, version := range database.Migrations {
+		if i > 0 && version == database.Migrations[i-1] {
+			t.Errorf("migration %d is numbered twice", version)
+		}
+		down, err := fs.Glob(migration.FS, fmt.Sprintf("%06d_*.down.sql", version))
+		if err != nil || len(down) != 1 {
+			t.Errorf("migration %d has no down migration", version)
+		}
+	}
+}
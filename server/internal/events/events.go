@@ -0,0 +1,82 @@
+package events
+
+import "simple_bank/server/internal/models"
+
+// Event types written to the outbox
+const (
+	AccountOpened     = "AccountOpened"
+	AccountFrozen     = "AccountFrozen"
+	AccountUnfrozen   = "AccountUnfrozen"
+	AccountClosed     = "AccountClosed"
+	BalanceChanged    = "BalanceChanged"
+	TransferCompleted = "TransferCompleted"
+)
+
+// Aggregate types events belong to
+const (
+	AggregateAccount  = "account"
+	AggregateTransfer = "transfer"
+)
+
+// Causes of a BalanceChanged event
+const (
+	CauseOpening    = "opening"
+	CauseTransfer   = "transfer"
+	CauseAdjustment = "adjustment"
+	CauseOverride   = "override"
+	CauseReversal   = "reversal"
+)
+
+type AccountOpenedPayload struct {
+	AccountID int64  `json:"account_id"`
+	Owner     string `json:"owner"`
+	Currency  string `json:"currency"`
+	Balance   int64  `json:"balance"`
+}
+
+type AccountStatusPayload struct {
+	AccountID int64  `json:"account_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type AccountClosedPayload struct {
+	AccountID int64  `json:"account_id"`
+	Owner     string `json:"owner"`
+}
+
+type BalanceChangedPayload struct {
+	AccountID int64  `json:"account_id"`
+	Currency  string `json:"currency"`
+	// Amount is the signed change applied to the balance
+	Amount  int64  `json:"amount"`
+	Balance int64  `json:"balance"`
+	Cause   string `json:"cause"`
+	// EntryID is the ledger entry recording the change, when there is one
+	EntryID    int64 `json:"entry_id,omitempty"`
+	TransferID int64 `json:"transfer_id,omitempty"`
+}
+
+type TransferCompletedPayload struct {
+	TransferID    int64  `json:"transfer_id"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// ReversalOf is the transfer a reversal sent back
+	ReversalOf int64 `json:"reversal_of,omitempty"`
+}
+
+// NewEvent builds an outbox event with a JSON payload
+func NewEvent(eventType, aggregateType string, aggregateID int64, payload any) (*models.OutboxEvent, error) {
+	data, err := models.NewJSON(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OutboxEvent{
+		EventType:     eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+	}, nil
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/metrics"
+	"simple_bank/server/internal/repositories"
+)
+
+// Relay delivers outbox events to sinks with at-least-once semantics: a
+// batch is only marked published after every sink accepted it. Only one
+// replica relays at a time.
+//
+// Events are ordered per aggregate, not globally. IDs are assigned when an
+// event is written, not when its transaction commits, so a later ID can
+// commit first. Writers take the aggregate's row lock before writing its
+// events, which makes ID order the commit order within an aggregate.
+type Relay struct {
+	uow          repositories.UnitOfWork
+	sinks        []Sink
+	batchSize    int
+	pollInterval time.Duration
+	wake         chan struct{}
+	heartbeat    health.Heartbeat
+	log          *slog.Logger
+}
+
+func NewRelay(uow repositories.UnitOfWork, batchSize int, pollInterval time.Duration, sinks ...Sink) *Relay {
+	return &Relay{
+		uow:          uow,
+		sinks:        sinks,
+		batchSize:    batchSize,
+		pollInterval: pollInterval,
+		wake:         make(chan struct{}, 1),
+		log:          logging.For("relay"),
+	}
+}
+
+// Wake makes Run relay immediately instead of waiting for the next tick.
+// It never blocks; wake-ups that arrive while one is pending are merged.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Heartbeat beats every time Run polls for events
+func (r *Relay) Heartbeat() *health.Heartbeat {
+	return &r.heartbeat
+}
+
+// Run relays events until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the backlog before waiting for the next tick
+		for {
+			r.heartbeat.Beat()
+			n, err := r.RelayOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					r.log.ErrorContext(ctx, "relaying outbox events", "error", err)
+				}
+				break
+			}
+			if n < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RelayOnce delivers a single batch and returns how many events it held
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	delivered := 0
+
+	err := r.uow.Do(ctx, func(tx *repositories.Repository) error {
+		locked, err := tx.Outbox.TryLockRelay()
+		if err != nil {
+			return err
+		}
+		if !locked {
+			// Another replica is relaying
+			return nil
+		}
+
+		batch, err := tx.Outbox.LockPending(r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			metrics.ObserveLag("relay", time.Time{})
+			return nil
+		}
+
+		// The batch is grouped by aggregate, so the oldest event can be anywhere
+		oldest := batch[0].CreatedAt
+		ids := make([]int64, len(batch))
+		for i, event := range batch {
+			ids[i] = event.ID
+			if event.CreatedAt.Before(oldest) {
+				oldest = event.CreatedAt
+			}
+		}
+		metrics.ObserveLag("relay", oldest)
+
+		for _, sink := range r.sinks {
+			if err := sink.Deliver(ctx, batch); err != nil {
+				return fmt.Errorf("sink %s: %w", sink.Name(), err)
+			}
+		}
+
+		if err := tx.Outbox.MarkPublished(ids, time.Now()); err != nil {
+			return err
+		}
+
+		delivered = len(batch)
+		return nil
+	})
+
+	return delivered, err
+}
@@ -0,0 +1,179 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+)
+
+// fakeOutbox keeps events in memory. Its relay lock is held by one unit of
+// work at a time, like the advisory lock.
+type fakeOutbox struct {
+	mu        sync.Mutex
+	events    []models.OutboxEvent
+	published map[int64]bool
+	lockedBy  *outboxTx
+}
+
+func newFakeOutbox(n int) *fakeOutbox {
+	o := &fakeOutbox{published: make(map[int64]bool)}
+	for i := range n {
+		o.events = append(o.events, models.OutboxEvent{ID: int64(i + 1), AggregateType: events.AggregateAccount, AggregateID: 1, CreatedAt: time.Now()})
+	}
+	return o
+}
+
+func (o *fakeOutbox) Do(ctx context.Context, fn func(tx *repositories.Repository) error) error {
+	tx := &outboxTx{outbox: o}
+	defer tx.release()
+	return fn(&repositories.Repository{Outbox: tx})
+}
+
+func (o *fakeOutbox) pending() []int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var ids []int64
+	for _, event := range o.events {
+		if !o.published[event.ID] {
+			ids = append(ids, event.ID)
+		}
+	}
+	return ids
+}
+
+// outboxTx is the outbox as seen from one unit of work
+type outboxTx struct {
+	repositories.OutboxRepository
+	outbox *fakeOutbox
+}
+
+func (tx *outboxTx) TryLockRelay() (bool, error) {
+	tx.outbox.mu.Lock()
+	defer tx.outbox.mu.Unlock()
+	if tx.outbox.lockedBy != nil && tx.outbox.lockedBy != tx {
+		return false, nil
+	}
+	tx.outbox.lockedBy = tx
+	return true, nil
+}
+
+func (tx *outboxTx) release() {
+	tx.outbox.mu.Lock()
+	defer tx.outbox.mu.Unlock()
+	if tx.outbox.lockedBy == tx {
+		tx.outbox.lockedBy = nil
+	}
+}
+
+func (tx *outboxTx) LockPending(limit int) ([]models.OutboxEvent, error) {
+	tx.outbox.mu.Lock()
+	defer tx.outbox.mu.Unlock()
+	var batch []models.OutboxEvent
+	for _, event := range tx.outbox.events {
+		if !tx.outbox.published[event.ID] && len(batch) < limit {
+			batch = append(batch, event)
+		}
+	}
+	return batch, nil
+}
+
+func (tx *outboxTx) MarkPublished(ids []int64, at time.Time) error {
+	tx.outbox.mu.Lock()
+	defer tx.outbox.mu.Unlock()
+	for _, id := range ids {
+		tx.outbox.published[id] = true
+	}
+	return nil
+}
+
+// recordingSink keeps the IDs of the events delivered to it
+type recordingSink struct {
+	mu  sync.Mutex
+	ids []int64
+}
+
+func (s *recordingSink) sink() events.Sink {
+	return events.NewFuncSink("recording", func(ctx context.Context, batch []models.OutboxEvent) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, event := range batch {
+			s.ids = append(s.ids, event.ID)
+		}
+		return nil
+	})
+}
+
+func TestRelayOnceMarksEventsPublished(t *testing.T) {
+	outbox := newFakeOutbox(3)
+	delivered := &recordingSink{}
+	relay := events.NewRelay(outbox, 2, time.Second, delivered.sink())
+
+	for _, want := range []int{2, 1, 0} {
+		n, err := relay.RelayOnce(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("relayed %d events, want %d", n, want)
+		}
+	}
+	if !slices.Equal(delivered.ids, []int64{1, 2, 3}) || len(outbox.pending()) != 0 {
+		t.Errorf("delivered %v, left %v pending", delivered.ids, outbox.pending())
+	}
+}
+
+func TestRelayOnceLeavesEventsPendingWhenASinkFails(t *testing.T) {
+	outbox := newFakeOutbox(2)
+	delivered := &recordingSink{}
+	failing := events.NewFuncSink("failing", func(context.Context, []models.OutboxEvent) error {
+		return errors.New("connection refused")
+	})
+	relay := events.NewRelay(outbox, 10, time.Second, delivered.sink(), failing)
+
+	if _, err := relay.RelayOnce(context.Background()); err == nil {
+		t.Fatal("expected the failing sink's error")
+	}
+	if !slices.Equal(outbox.pending(), []int64{1, 2}) {
+		t.Errorf("got %v pending, want the whole batch to be retried", outbox.pending())
+	}
+}
+
+func TestConcurrentRelaysPublishOnce(t *testing.T) {
+	outbox := newFakeOutbox(2)
+	delivered := &recordingSink{}
+	entered, proceed := make(chan struct{}), make(chan struct{})
+	blocking := events.NewFuncSink("blocking", func(context.Context, []models.OutboxEvent) error {
+		close(entered)
+		<-proceed
+		return nil
+	})
+	first := events.NewRelay(outbox, 10, time.Second, delivered.sink(), blocking)
+	second := events.NewRelay(outbox, 10, time.Second, delivered.sink())
+
+	var firstErr error
+	var wg sync.WaitGroup
+	wg.Go(func() { _, firstErr = first.RelayOnce(context.Background()) })
+
+	// The second relay runs while the first holds the lock mid-batch
+	<-entered
+	n, err := second.RelayOnce(context.Background())
+	if err != nil || n != 0 {
+		t.Errorf("second relay delivered %d events (%v) while the first held the lock", n, err)
+	}
+	close(proceed)
+	wg.Wait()
+	if firstErr != nil {
+		t.Fatal(firstErr)
+	}
+
+	if !slices.Equal(delivered.ids, []int64{1, 2}) || len(outbox.pending()) != 0 {
+		t.Errorf("delivered %v, left %v pending", delivered.ids, outbox.pending())
+	}
+}
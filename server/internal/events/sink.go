@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/models"
+)
+
+// Sink receives outbox events from the relay. Each aggregate's events arrive
+// in ID order, but events of different aggregates may not, and events may
+// be delivered more than once, so sinks must be idempotent on the event ID.
+// Returning an error makes the relay retry the whole batch.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, events []models.OutboxEvent) error
+}
+
+type logSink struct {
+	log *slog.Logger
+}
+
+// NewLogSink returns a sink that writes every event to the log at debug
+// level, with owner names and secrets in payloads redacted
+func NewLogSink() Sink {
+	return logSink{log: logging.For("events")}
+}
+
+func (logSink) Name() string {
+	return "log"
+}
+
+func (s logSink) Deliver(ctx context.Context, events []models.OutboxEvent) error {
+	if !s.log.Enabled(ctx, slog.LevelDebug) {
+		return nil
+	}
+	for _, event := range events {
+		s.log.DebugContext(ctx, "event",
+			"id", event.ID,
+			"type", event.EventType,
+			"aggregate_type", event.AggregateType,
+			"aggregate_id", event.AggregateID,
+			"payload", logging.RedactJSON(event.Payload),
+		)
+	}
+	return nil
+}
+
+type funcSink struct {
+	name string
+	fn   func(ctx context.Context, events []models.OutboxEvent) error
+}
+
+// NewFuncSink adapts a function to the Sink interface
+func NewFuncSink(name string, fn func(ctx context.Context, events []models.OutboxEvent) error) Sink {
+	return funcSink{name: name, fn: fn}
+}
+
+func (s funcSink) Name() string {
+	return s.name
+}
+
+func (s funcSink) Deliver(ctx context.Context, events []models.OutboxEvent) error {
+	return s.fn(ctx, events)
+}
@@ -0,0 +1,388 @@
+package graphapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/graph-gophers/graphql-go/ast"
+)
+
+// complexity estimates the cost of op: every field costs one, and the
+// fields below a connection count once per item it may return
+func complexity(schema *ast.Schema, doc *ast.ExecutableDefinition, op *ast.OperationDefinition, variables map[string]any) int {
+	c := &costing{schema: schema, doc: doc, op: op, variables: variables, visiting: map[string]bool{}}
+	return c.selectionCost(op.Selections, schema.RootOperationTypes[strings.ToLower(string(op.Type))])
+}
+
+type costing struct {
+	schema    *ast.Schema
+	doc       *ast.ExecutableDefinition
+	op        *ast.OperationDefinition
+	variables map[string]any
+	visiting  map[string]bool
+}
+
+// selectionCost is the cost of set selected on parent, which is nil when
+// the query names a type or field the schema lacks; execution rejects those
+func (c *costing) selectionCost(set ast.SelectionSet, parent ast.NamedType) int {
+	cost := 0
+	for _, selection := range set {
+		switch s := selection.(type) {
+		case *ast.Field:
+			var def *ast.FieldDefinition
+			if object, ok := parent.(*ast.ObjectTypeDefinition); ok {
+				def = object.Fields.Get(s.Name.Name)
+			}
+			cost += 1 + c.multiplier(def, s)*c.selectionCost(s.SelectionSet, namedType(def))
+		case *ast.InlineFragment:
+			cost += c.selectionCost(s.Selections, c.typeCondition(s.On, parent))
+		case *ast.FragmentSpread:
+			// Validation rejects cycles, but only once the query executes
+			fragment := c.doc.Fragments.Get(s.Name.Name)
+			if fragment == nil || c.visiting[s.Name.Name] {
+				continue
+			}
+			c.visiting[s.Name.Name] = true
+			cost += c.selectionCost(fragment.Selections, c.typeCondition(fragment.On, parent))
+			delete(c.visiting, s.Name.Name)
+		}
+	}
+	return cost
+}
+
+func (c *costing) typeCondition(on ast.TypeName, parent ast.NamedType) ast.NamedType {
+	if on.Name == "" {
+		return parent
+	}
+	return c.schema.Types[on.Name]
+}
+
+// multiplier is the page size a connection field asks for, or 1
+func (c *costing) multiplier(def *ast.FieldDefinition, field *ast.Field) int {
+	if def == nil || def.Arguments.Get("first") == nil {
+		return 1
+	}
+
+	first := int32(defaultFirst)
+	if arg, ok := field.Arguments.Get("first"); ok {
+		switch n := c.value(arg).(type) {
+		case int64:
+			first = int32(min(max(n, 0), maxFirst))
+		case float64:
+			first = int32(min(max(n, 0), maxFirst))
+		}
+	}
+	return pageSize(&first)
+}
+
+// value is an integer argument as a literal or variable, or nil
+func (c *costing) value(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.Variable:
+		if value, ok := c.variables[v.Name]; ok {
+			return value
+		}
+		if def := c.op.Vars.Get(v.Name); def != nil && def.Default != nil {
+			return c.value(def.Default)
+		}
+	case *ast.PrimitiveValue:
+		if n, err := strconv.ParseInt(v.Text, 10, 64); err == nil && v.Type == scanner.Int {
+			return n
+		}
+	}
+	return nil
+}
+
+// namedType is the type a field's items have, or nil
+func namedType(def *ast.FieldDefinition) ast.NamedType {
+	if def == nil {
+		return nil
+	}
+	t := def.Type
+	for {
+		switch wrapped := t.(type) {
+		case *ast.NonNull:
+			t = wrapped.OfType
+		case *ast.List:
+			t = wrapped.OfType
+		default:
+			named, _ := t.(ast.NamedType)
+			return named
+		}
+	}
+}
+
+// syntaxError is a query the parser below cannot read
+type syntaxError string
+
+func (e syntaxError) Error() string { return "syntax error: " + string(e) }
+
+// parseQuery reads the operations and fragments of a query document. The
+// executing library keeps its parser internal, so this one follows its
+// grammar token for token; TestParseQueryAgreesWithTheLibrary holds the two
+// to the same answers.
+func parseQuery(query string) (doc *ast.ExecutableDefinition, err error) {
+	p := &parser{}
+	p.sc.Init(strings.NewReader(query))
+	// The library asks for identifiers, numbers and strings, but calls Init
+	// afterwards, which resets the mode; what it scans with is GoTokens, so
+	// Go comments and raw strings are tokens there too
+	p.sc.Mode = scanner.GoTokens
+	p.sc.Error = func(_ *scanner.Scanner, msg string) { p.fail("%s", msg) }
+	defer func() {
+		if r := recover(); r != nil {
+			syntax, ok := r.(syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = syntax
+		}
+	}()
+
+	doc = &ast.ExecutableDefinition{}
+	p.next()
+	for p.tok != scanner.EOF {
+		if p.tok == '{' {
+			doc.Operations = append(doc.Operations, &ast.OperationDefinition{Type: "QUERY", Selections: p.selectionSet()})
+			continue
+		}
+		switch keyword := p.name().Name; keyword {
+		case "query", "mutation", "subscription":
+			doc.Operations = append(doc.Operations, p.operation(ast.OperationType(strings.ToUpper(keyword))))
+		case "fragment":
+			doc.Fragments = append(doc.Fragments, p.fragment())
+		default:
+			p.fail(`unexpected %q, expecting "fragment"`, keyword)
+		}
+	}
+	return doc, nil
+}
+
+type parser struct {
+	sc  scanner.Scanner
+	tok rune
+}
+
+// next moves to the next token, skipping commas and # comments
+func (p *parser) next() {
+	for {
+		p.tok = p.sc.Scan()
+		switch p.tok {
+		case ',':
+			continue
+		case '#':
+			for c := p.sc.Peek(); c != '\n' && c != '\r' && c != scanner.EOF; c = p.sc.Peek() {
+				p.sc.Next()
+			}
+			continue
+		}
+		return
+	}
+}
+
+func (p *parser) fail(format string, args ...any) {
+	panic(syntaxError(fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) expect(tok rune) {
+	if p.tok != tok {
+		p.fail("unexpected %q, expecting %s", p.sc.TokenText(), scanner.TokenString(tok))
+	}
+	p.next()
+}
+
+func (p *parser) name() ast.Ident {
+	text := p.sc.TokenText()
+	p.expect(scanner.Ident)
+	return ast.Ident{Name: text}
+}
+
+func (p *parser) operation(opType ast.OperationType) *ast.OperationDefinition {
+	op := &ast.OperationDefinition{Type: opType}
+	if p.tok == scanner.Ident {
+		op.Name = p.name()
+	}
+	if p.tok == '(' {
+		p.next()
+		for p.tok != ')' {
+			p.expect('$')
+			p.description()
+			v := &ast.InputValueDefinition{Name: p.name()}
+			p.expect(':')
+			p.typeRef()
+			if p.tok == '=' {
+				p.next()
+				v.Default = p.value(true)
+			}
+			p.directives()
+			op.Vars = append(op.Vars, v)
+		}
+		p.next()
+	}
+	p.directives()
+	op.Selections = p.selectionSet()
+	return op
+}
+
+func (p *parser) fragment() *ast.FragmentDefinition {
+	f := &ast.FragmentDefinition{Name: p.name()}
+	if on := p.name().Name; on != "on" {
+		p.fail(`unexpected %q, expecting "on"`, on)
+	}
+	f.On = ast.TypeName{Ident: p.name()}
+	p.directives()
+	f.Selections = p.selectionSet()
+	return f
+}
+
+func (p *parser) selectionSet() ast.SelectionSet {
+	var set ast.SelectionSet
+	p.expect('{')
+	for p.tok != '}' {
+		set = append(set, p.selection())
+	}
+	p.next()
+	return set
+}
+
+func (p *parser) selection() ast.Selection {
+	if p.tok == '.' {
+		p.expect('.')
+		p.expect('.')
+		p.expect('.')
+		inline := &ast.InlineFragment{}
+		if p.tok == scanner.Ident {
+			name := p.name()
+			if name.Name != "on" {
+				p.directives()
+				return &ast.FragmentSpread{Name: name}
+			}
+			inline.On = ast.TypeName{Ident: p.name()}
+		}
+		p.directives()
+		inline.Selections = p.selectionSet()
+		return inline
+	}
+
+	f := &ast.Field{Alias: p.name()}
+	f.Name = f.Alias
+	if p.tok == ':' {
+		p.next()
+		f.Name = p.name()
+	}
+	if p.tok == '(' {
+		f.Arguments = p.arguments()
+	}
+	p.directives()
+	if p.tok == '{' {
+		f.SelectionSet = p.selectionSet()
+	}
+	return f
+}
+
+func (p *parser) arguments() ast.ArgumentList {
+	var args ast.ArgumentList
+	p.expect('(')
+	for p.tok != ')' {
+		name := p.name()
+		p.expect(':')
+		args = append(args, &ast.Argument{Name: name, Value: p.value(false)})
+		p.directives()
+	}
+	p.next()
+	return args
+}
+
+// directives are read and dropped; none changes what a query costs
+func (p *parser) directives() {
+	for p.tok == '@' {
+		p.next()
+		p.name()
+		if p.tok == '(' {
+			p.arguments()
+		}
+	}
+}
+
+// description skips the string the library lets precede a variable's
+// name. The scanner reads the opening quotes of """ as an empty string.
+func (p *parser) description() {
+	if p.tok != scanner.String {
+		return
+	}
+	if p.sc.Peek() == '"' {
+		p.sc.Next()
+		for quotes := 0; quotes < 3; {
+			c := p.sc.Next()
+			if c == scanner.EOF {
+				break
+			}
+			if c == '"' {
+				quotes++
+			} else {
+				quotes = 0
+			}
+		}
+	}
+	p.next()
+}
+
+// typeRef reads a variable's type, which costing does not need
+func (p *parser) typeRef() {
+	if p.tok == '[' {
+		p.next()
+		p.typeRef()
+		p.expect(']')
+	} else {
+		p.name()
+	}
+	if p.tok == '!' {
+		p.next()
+	}
+}
+
+func (p *parser) value(constOnly bool) ast.Value {
+	switch p.tok {
+	case '$':
+		if constOnly {
+			p.fail("variable not allowed")
+		}
+		p.next()
+		return &ast.Variable{Name: p.name().Name}
+	case scanner.Int, scanner.Float, scanner.String, scanner.Ident:
+		lit := &ast.PrimitiveValue{Type: p.tok, Text: p.sc.TokenText()}
+		p.next()
+		if lit.Type == scanner.Ident && lit.Text == "null" {
+			return &ast.NullValue{}
+		}
+		return lit
+	case '-':
+		// Any token may follow the sign; validation rejects what is not a number
+		p.next()
+		lit := &ast.PrimitiveValue{Type: p.tok, Text: "-" + p.sc.TokenText()}
+		p.next()
+		return lit
+	case '[':
+		p.next()
+		list := &ast.ListValue{}
+		for p.tok != ']' {
+			list.Values = append(list.Values, p.value(constOnly))
+		}
+		p.next()
+		return list
+	case '{':
+		p.next()
+		object := &ast.ObjectValue{}
+		for p.tok != '}' {
+			name := p.name()
+			p.expect(':')
+			object.Fields = append(object.Fields, &ast.ObjectField{Name: name, Value: p.value(constOnly)})
+		}
+		p.next()
+		return object
+	}
+	p.fail("invalid value")
+	return nil
+}
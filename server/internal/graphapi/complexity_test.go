@@ -0,0 +1,48 @@
+package graphapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// TestParseQueryAgreesWithTheLibrary checks that parseQuery reads what the
+// executing library reads: a query one side cannot parse would be costed
+// differently from how it runs
+func TestParseQueryAgreesWithTheLibrary(t *testing.T) {
+	schema := graphql.MustParseSchema(schemaSDL, nil, graphql.UseStringDescriptions())
+
+	for _, query := range []string{
+		`{ account(id: "1") { id } }`,
+		`query { accounts(first: 5, after: "a\"b") { edges { node { id } } } }`,
+		`query Q($n: Int = 5, $after: String) @skip(if: false) { accounts(first: $n, after: $after) { ...Page } }
+		fragment Page on AccountConnection { edges { ... on AccountEdge { node { id } } } }`,
+		`# a comment
+		{ a: account(id: 1), b: account(id: -1.5) { id } }`,
+		`// a Go comment
+		{ account(id: "1") { /* inline */ id } }`,
+		`{ account(id: [1, {x: null, y: ENUM}]) { id } }`,
+		`{ account(id: -abc) { id } }`,
+		`query($"described" n: Int) { account(id: $n) { id } }`,
+		`query($"""block "" described""" n: Int) { account(id: $n) { id } }`,
+		`{ account(id: """block""") { id } }`,
+		`{ account(id: "1") { id }`,
+		`{ account(id: ) { id } }`,
+		`{ account(id: 'c') { id } }`,
+		"{ account(id: `raw`) { id } }",
+		`{ account(id: "unterminated) { id } }`,
+		`query Q($n: Int = $m) { account(id: $n) { id } }`,
+		`fragment F Account { id }`,
+		`subscription { account(id: "1") { id } }`,
+		`{ account(id: "1") { .. id } }`,
+		`schema { query: Query }`,
+	} {
+		_, err := parseQuery(query)
+		errs := schema.Validate(query)
+		librarySyntaxError := len(errs) > 0 && strings.HasPrefix(errs[0].Message, "syntax error")
+		if (err != nil) != librarySyntaxError {
+			t.Errorf("%s\nparseQuery: %v, library: %v", query, err, errs)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package graphapi
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	defaultFirst = 10
+	// maxFirst bounds page sizes; complexity is estimated from it too
+	maxFirst = 50
+)
+
+// pageSize clamps the first argument of a connection
+func pageSize(first *int32) int {
+	if first == nil || *first < 1 {
+		return defaultFirst
+	}
+	return min(int(*first), maxFirst)
+}
+
+// connectionArgs are the arguments of every connection field. After is
+// the cursor of an edge, which only continues a listing in the same order.
+type connectionArgs struct {
+	First *int32
+	After *string
+	Order string
+}
+
+// sortedConnectionArgs also choose the field to sort by
+type sortedConnectionArgs struct {
+	connectionArgs
+	Sort string
+}
+
+// pageRequest translates the arguments for the services; sort is the
+// schema's SortField value, such as CREATED_AT
+func (a connectionArgs) pageRequest(sort string) services.PageRequest {
+	req := services.PageRequest{
+		PageSize:  pageSize(a.First),
+		Sort:      repositories.SortField(strings.ToLower(sort)),
+		Ascending: a.Order == "ASC",
+	}
+	if a.After != nil {
+		req.Cursor = *a.After
+	}
+	return req
+}
+
+type pageInfo struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (p pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+func parseID(id graphql.ID) (int64, error) {
+	n, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid ID")
+	}
+	return n, nil
+}
+
+func formatID(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
@@ -0,0 +1,69 @@
+package graphapi
+
+import (
+	"context"
+	_ "embed"
+
+	"simple_bank/server/internal/services"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+)
+
+// Query limits
+const (
+	MaxDepth      = 10
+	MaxComplexity = 2500
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Server executes read-only queries over accounts, entries and transfers
+type Server struct {
+	services *services.Services
+	schema   *graphql.Schema
+}
+
+func NewServer(services *services.Services) *Server {
+	return &Server{
+		services: services,
+		schema: graphql.MustParseSchema(schemaSDL, &resolver{services: services},
+			graphql.UseStringDescriptions(),
+			graphql.MaxDepth(MaxDepth),
+			// Resolve a whole page concurrently so loaders see it as one batch
+			graphql.MaxParallelism(maxFirst),
+		),
+	}
+}
+
+// Execute runs req for the caller in ctx
+func (s *Server) Execute(ctx context.Context, req Request) *graphql.Response {
+	doc, err := parseQuery(req.Query)
+	if err != nil {
+		return &graphql.Response{Errors: []*errors.QueryError{errors.Errorf("%s", err)}}
+	}
+
+	op := doc.Operations.Get(req.OperationName)
+	if req.OperationName == "" && len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	}
+	if op == nil {
+		return &graphql.Response{Errors: []*errors.QueryError{errors.Errorf("unknown operation %q", req.OperationName)}}
+	}
+	if cost := complexity(s.schema.AST(), doc, op, req.Variables); cost > MaxComplexity {
+		return &graphql.Response{Errors: []*errors.QueryError{
+			errors.Errorf("query complexity %d exceeds the limit of %d", cost, MaxComplexity),
+		}}
+	}
+
+	ctx = withLoaders(ctx, s.services)
+	return s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
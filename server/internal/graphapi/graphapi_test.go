@@ -0,0 +1,173 @@
+package graphapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/graphapi"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+)
+
+const accountCount = 20
+
+type fakeAccounts struct {
+	services.AccountService
+	calls atomic.Int32
+}
+
+func (f *fakeAccounts) GetAccountsByIDs(ctx context.Context, ids []int64) ([]models.Account, error) {
+	f.calls.Add(1)
+	var accounts []models.Account
+	for _, id := range ids {
+		accounts = append(accounts, models.Account{ID: id, Owner: "alice", Currency: "USD"})
+	}
+	return accounts, nil
+}
+
+func (f *fakeAccounts) GetAccountsByOwner(ctx context.Context, owner string, req services.PageRequest) (*services.Page[models.Account], error) {
+	var accounts []models.Account
+	for id := int64(1); id <= accountCount && len(accounts) < req.PageSize; id++ {
+		accounts = append(accounts, models.Account{ID: id, Owner: owner, Currency: "USD"})
+	}
+	return &services.Page[models.Account]{Items: accounts}, nil
+}
+
+// fakeEntries gives every account one entry from a transfer with the
+// account numbered 100 higher, and keeps the page requests it was asked for
+type fakeEntries struct {
+	services.EntryService
+	calls    atomic.Int32
+	requests []services.PageRequest
+}
+
+func (f *fakeEntries) ListEntriesForAccounts(ctx context.Context, accountIDs []int64, req services.PageRequest) (map[int64]*services.Page[models.Entry], error) {
+	f.calls.Add(1)
+	f.requests = append(f.requests, req)
+	pages := map[int64]*services.Page[models.Entry]{}
+	for _, id := range accountIDs {
+		transferID := id
+		pages[id] = &services.Page[models.Entry]{Items: []models.Entry{{ID: id, AccountID: id, Amount: -5, TransferID: &transferID}}}
+	}
+	return pages, nil
+}
+
+type fakeTransfers struct {
+	services.TransferService
+	calls atomic.Int32
+}
+
+func (f *fakeTransfers) GetTransfersByIDs(ctx context.Context, ids []int64) ([]models.Transfer, error) {
+	f.calls.Add(1)
+	var transfers []models.Transfer
+	for _, id := range ids {
+		transfers = append(transfers, models.Transfer{ID: id, FromAccountID: id, ToAccountID: id + 100, Amount: 5})
+	}
+	return transfers, nil
+}
+
+func TestBatchesNestedLoads(t *testing.T) {
+	accounts, entries, transfers := &fakeAccounts{}, &fakeEntries{}, &fakeTransfers{}
+	server := graphapi.NewServer(&services.Services{Account: accounts, Entry: entries, Transfer: transfers})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Role: auth.RoleCustomer})
+
+	resp := server.Execute(ctx, graphapi.Request{Query: `{
+		accounts(first: 20) {
+			edges { node { id entries(first: 1) { edges { node { amount counterparty { id } } } } } }
+			pageInfo { hasNextPage }
+		}
+	}`})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+
+	var data struct {
+		Accounts struct {
+			Edges []struct {
+				Node struct {
+					ID      string
+					Entries struct {
+						Edges []struct {
+							Node struct {
+								Counterparty struct{ ID string }
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Accounts.Edges) != accountCount {
+		t.Fatalf("got %d accounts, want %d", len(data.Accounts.Edges), accountCount)
+	}
+	if got := data.Accounts.Edges[0].Node.Entries.Edges[0].Node.Counterparty.ID; got != "101" {
+		t.Fatalf("counterparty of account 1 is %q, want 101", got)
+	}
+
+	// Without batching each of these would run once per account
+	for name, calls := range map[string]int32{
+		"entries":   entries.calls.Load(),
+		"transfers": transfers.calls.Load(),
+		"accounts":  accounts.calls.Load(),
+	} {
+		if calls >= accountCount/2 {
+			t.Errorf("%s fetched %d times for %d accounts", name, calls, accountCount)
+		}
+	}
+}
+
+func TestConnectionsPassTheirSortOn(t *testing.T) {
+	entries := &fakeEntries{}
+	server := graphapi.NewServer(&services.Services{Account: &fakeAccounts{}, Entry: entries, Transfer: &fakeTransfers{}})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Role: auth.RoleCustomer})
+
+	resp := server.Execute(ctx, graphapi.Request{Query: `{
+		account(id: "1") { entries(first: 5, after: "abc", sort: AMOUNT, order: ASC) { edges { node { id } } } }
+	}`})
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	want := services.PageRequest{PageSize: 5, Cursor: "abc", Sort: repositories.SortAmount, Ascending: true}
+	if len(entries.requests) != 1 || entries.requests[0] != want {
+		t.Errorf("got page requests %+v, want %+v", entries.requests, want)
+	}
+}
+
+func TestRejectsComplexQueries(t *testing.T) {
+	server := graphapi.NewServer(&services.Services{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice", Role: auth.RoleCustomer})
+
+	for name, req := range map[string]graphapi.Request{
+		"nested": {Query: `{
+			accounts(first: 50) { edges { node {
+				transfers(first: 50) { edges { node { fromAccount { id owner balance } } } }
+			} } }
+		}`},
+		"fragments and variables": {Query: `query Q($n: Int = 50) {
+			accounts(first: $n) { ...Sent }
+		}
+		fragment Sent on AccountConnection { edges { node { transfers(first: $n) { edges { node { id amount } } } } } }`},
+		"aliases": {Query: `query Q($n: Int) {
+			a: accounts(first: $n) { edges { node { entries(first: $n) { edges { node { id } } } } } }
+			b: accounts(first: $n) { edges { node { entries(first: $n) { edges { node { id } } } } } }
+		}`, OperationName: "Q", Variables: map[string]any{"n": float64(40)}},
+	} {
+		resp := server.Execute(ctx, req)
+		if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "complexity") {
+			t.Errorf("%s: got errors %v, want a complexity error", name, resp.Errors)
+		}
+	}
+
+	resp := server.Execute(ctx, graphapi.Request{Query: `{ accounts(first: 5 { id } }`})
+	if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, "syntax error") {
+		t.Errorf("got errors %v, want a syntax error", resp.Errors)
+	}
+}
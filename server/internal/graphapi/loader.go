@@ -0,0 +1,92 @@
+package graphapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// batchWait is how long a loader collects keys before fetching them
+	batchWait = 2 * time.Millisecond
+	// maxBatch fetches early once this many keys are waiting
+	maxBatch = 100
+)
+
+// loader batches the keys requested by concurrently running resolvers into
+// one fetch, in the manner of the JavaScript dataloader, and caches results
+// for the rest of the request
+type loader[K comparable, V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	cache   map[K]*result[V]
+	pending []K
+	timer   *time.Timer
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	found bool
+	err   error
+}
+
+func newLoader[K comparable, V any](ctx context.Context, fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		ctx:   ctx,
+		fetch: fetch,
+		cache: map[K]*result[V]{},
+	}
+}
+
+// Load returns the value for key, reporting false if the fetch left it out
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, bool, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		l.pending = append(l.pending, key)
+
+		switch {
+		case len(l.pending) >= maxBatch:
+			l.timer.Stop()
+			go l.dispatch()
+		case len(l.pending) == 1:
+			l.timer = time.AfterFunc(batchWait, l.dispatch)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.found, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, false, ctx.Err()
+	}
+}
+
+// dispatch fetches every pending key in one batch
+func (l *loader[K, V]) dispatch() {
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+
+	values, err := l.fetch(l.ctx, keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		r := l.cache[key]
+		r.value, r.found = values[key]
+		r.err = err
+		close(r.done)
+	}
+}
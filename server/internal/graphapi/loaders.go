@@ -0,0 +1,83 @@
+package graphapi
+
+import (
+	"context"
+
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+)
+
+// pageKey asks for one page of an account's entries or transfers
+type pageKey struct {
+	accountID int64
+	req       services.PageRequest
+}
+
+// loaders are created per request so cached results never outlive the
+// caller they were authorized for
+type loaders struct {
+	accounts         *loader[int64, models.Account]
+	transfers        *loader[int64, models.Transfer]
+	accountEntries   *loader[pageKey, *services.Page[models.Entry]]
+	accountTransfers *loader[pageKey, *services.Page[models.Transfer]]
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, svc *services.Services) context.Context {
+	l := &loaders{
+		accounts: newLoader(ctx, func(ctx context.Context, ids []int64) (map[int64]models.Account, error) {
+			accounts, err := svc.Account.GetAccountsByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[int64]models.Account, len(accounts))
+			for _, account := range accounts {
+				byID[account.ID] = account
+			}
+			return byID, nil
+		}),
+		transfers: newLoader(ctx, func(ctx context.Context, ids []int64) (map[int64]models.Transfer, error) {
+			transfers, err := svc.Transfer.GetTransfersByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[int64]models.Transfer, len(transfers))
+			for _, transfer := range transfers {
+				byID[transfer.ID] = transfer
+			}
+			return byID, nil
+		}),
+		accountEntries:   newLoader(ctx, loadPages(svc.Entry.ListEntriesForAccounts)),
+		accountTransfers: newLoader(ctx, loadPages(svc.Transfer.ListTransfersForAccounts)),
+	}
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadPages adapts a per-account listing to page keys. Keys asking for the
+// same page of different accounts are fetched together, so the first page
+// of every account in a list costs one query.
+func loadPages[V any](list func(ctx context.Context, accountIDs []int64, req services.PageRequest) (map[int64]*services.Page[V], error)) func(context.Context, []pageKey) (map[pageKey]*services.Page[V], error) {
+	return func(ctx context.Context, keys []pageKey) (map[pageKey]*services.Page[V], error) {
+		groups := map[services.PageRequest][]int64{}
+		for _, key := range keys {
+			groups[key.req] = append(groups[key.req], key.accountID)
+		}
+
+		pages := make(map[pageKey]*services.Page[V], len(keys))
+		for req, accountIDs := range groups {
+			byAccount, err := list(ctx, accountIDs, req)
+			if err != nil {
+				return nil, err
+			}
+			for accountID, page := range byAccount {
+				pages[pageKey{accountID, req}] = page
+			}
+		}
+		return pages, nil
+	}
+}
@@ -0,0 +1,249 @@
+package graphapi
+
+import (
+	"context"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type resolver struct {
+	services *services.Services
+}
+
+// requireScope enforces API key scopes per field, since a single request
+// can read both accounts and transfers
+func requireScope(ctx context.Context, scope auth.Scope) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	if principal.IsService() && !principal.HasScope(scope) {
+		return apperr.New(apperr.CodeForbidden, "API key lacks the "+string(scope)+" scope")
+	}
+	return nil
+}
+
+func (r *resolver) Account(ctx context.Context, args struct{ ID graphql.ID }) (*accountResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return loadAccount(ctx, id)
+}
+
+func (r *resolver) Accounts(ctx context.Context, args struct {
+	Owner *string
+	connectionArgs
+}) (*connection[*accountResolver], error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	owner := principal.Subject
+	if args.Owner != nil {
+		owner = *args.Owner
+	}
+
+	page, err := r.services.Account.GetAccountsByOwner(ctx, owner, args.pageRequest(""))
+	if err != nil {
+		return nil, err
+	}
+	return newConnection(page, func(a models.Account) *accountResolver { return &accountResolver{a} }), nil
+}
+
+func (r *resolver) Transfer(ctx context.Context, args struct{ ID graphql.ID }) (*transferResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return loadTransfer(ctx, id)
+}
+
+// loadAccount returns nil for accounts the caller cannot view
+func loadAccount(ctx context.Context, id int64) (*accountResolver, error) {
+	account, found, err := loadersFrom(ctx).accounts.Load(ctx, id)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &accountResolver{account}, nil
+}
+
+// loadTransfer returns nil for transfers the caller cannot view
+func loadTransfer(ctx context.Context, id int64) (*transferResolver, error) {
+	if err := requireScope(ctx, auth.ScopeTransfersRead); err != nil {
+		return nil, err
+	}
+	transfer, found, err := loadersFrom(ctx).transfers.Load(ctx, id)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &transferResolver{transfer}, nil
+}
+
+type accountResolver struct {
+	account models.Account
+}
+
+func (a *accountResolver) ID() graphql.ID {
+	return formatID(a.account.ID)
+}
+
+func (a *accountResolver) Owner() string {
+	return a.account.Owner
+}
+
+func (a *accountResolver) Balance() Int64 {
+	return Int64(a.account.Balance)
+}
+
+func (a *accountResolver) Currency() string {
+	return a.account.Currency
+}
+
+func (a *accountResolver) Status() string {
+	return a.account.Status
+}
+
+func (a *accountResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: a.account.CreatedAt}
+}
+
+func (a *accountResolver) Entries(ctx context.Context, args sortedConnectionArgs) (*connection[*entryResolver], error) {
+	page, _, err := loadersFrom(ctx).accountEntries.Load(ctx, pageKey{a.account.ID, args.pageRequest(args.Sort)})
+	if err != nil {
+		return nil, err
+	}
+	return newConnection(page, func(e models.Entry) *entryResolver { return &entryResolver{e} }), nil
+}
+
+func (a *accountResolver) Transfers(ctx context.Context, args sortedConnectionArgs) (*connection[*transferResolver], error) {
+	if err := requireScope(ctx, auth.ScopeTransfersRead); err != nil {
+		return nil, err
+	}
+	page, _, err := loadersFrom(ctx).accountTransfers.Load(ctx, pageKey{a.account.ID, args.pageRequest(args.Sort)})
+	if err != nil {
+		return nil, err
+	}
+	return newConnection(page, func(t models.Transfer) *transferResolver { return &transferResolver{t} }), nil
+}
+
+type entryResolver struct {
+	entry models.Entry
+}
+
+func (e *entryResolver) ID() graphql.ID {
+	return formatID(e.entry.ID)
+}
+
+func (e *entryResolver) Amount() Int64 {
+	return Int64(e.entry.Amount)
+}
+
+func (e *entryResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: e.entry.CreatedAt}
+}
+
+func (e *entryResolver) Account(ctx context.Context) (*accountResolver, error) {
+	return loadAccount(ctx, e.entry.AccountID)
+}
+
+func (e *entryResolver) Transfer(ctx context.Context) (*transferResolver, error) {
+	if e.entry.TransferID == nil {
+		return nil, nil
+	}
+	return loadTransfer(ctx, *e.entry.TransferID)
+}
+
+func (e *entryResolver) Counterparty(ctx context.Context) (*accountResolver, error) {
+	transfer, err := e.Transfer(ctx)
+	if err != nil || transfer == nil {
+		return nil, err
+	}
+
+	counterpartyID := transfer.transfer.FromAccountID
+	if counterpartyID == e.entry.AccountID {
+		counterpartyID = transfer.transfer.ToAccountID
+	}
+	return loadAccount(ctx, counterpartyID)
+}
+
+type transferResolver struct {
+	transfer models.Transfer
+}
+
+func (t *transferResolver) ID() graphql.ID {
+	return formatID(t.transfer.ID)
+}
+
+func (t *transferResolver) Amount() Int64 {
+	return Int64(t.transfer.Amount)
+}
+
+func (t *transferResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: t.transfer.CreatedAt}
+}
+
+func (t *transferResolver) FromAccountID() graphql.ID {
+	return formatID(t.transfer.FromAccountID)
+}
+
+func (t *transferResolver) ToAccountID() graphql.ID {
+	return formatID(t.transfer.ToAccountID)
+}
+
+func (t *transferResolver) FromAccount(ctx context.Context) (*accountResolver, error) {
+	return loadAccount(ctx, t.transfer.FromAccountID)
+}
+
+func (t *transferResolver) ToAccount(ctx context.Context) (*accountResolver, error) {
+	return loadAccount(ctx, t.transfer.ToAccountID)
+}
+
+type connection[N any] struct {
+	edges []edge[N]
+	info  pageInfo
+}
+
+func (c *connection[N]) Edges() []edge[N] {
+	return c.edges
+}
+
+func (c *connection[N]) PageInfo() pageInfo {
+	return c.info
+}
+
+type edge[N any] struct {
+	cursor string
+	node   N
+}
+
+func (e edge[N]) Cursor() string {
+	return e.cursor
+}
+
+func (e edge[N]) Node() N {
+	return e.node
+}
+
+// newConnection turns a page into a connection; page is nil for accounts
+// the caller cannot view
+func newConnection[T, N any](page *services.Page[T], node func(T) N) *connection[N] {
+	c := &connection[N]{edges: []edge[N]{}}
+	if page == nil {
+		return c
+	}
+	c.info.hasNextPage = page.NextCursor != ""
+	for i, item := range page.Items {
+		c.edges = append(c.edges, edge[N]{cursor: page.CursorAt(i), node: node(item)})
+	}
+	if n := len(c.edges); n > 0 {
+		end := c.edges[n-1].cursor
+		c.info.endCursor = &end
+	}
+	return c
+}
@@ -0,0 +1,43 @@
+package graphapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Int64 is the Int64 scalar. GraphQL's Int is 32-bit, too small for
+// balances in minor units.
+type Int64 int64
+
+func (Int64) ImplementsGraphQLType(name string) bool {
+	return name == "Int64"
+}
+
+func (i *Int64) UnmarshalGraphQL(input any) error {
+	switch v := input.(type) {
+	case int32:
+		*i = Int64(v)
+	case int64:
+		*i = Int64(v)
+	case float64:
+		if v != math.Trunc(v) {
+			return fmt.Errorf("Int64 cannot represent %v", v)
+		}
+		*i = Int64(v)
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Int64 cannot represent %q", v)
+		}
+		*i = Int64(n)
+	default:
+		return fmt.Errorf("Int64 cannot represent %T", input)
+	}
+	return nil
+}
+
+func (i Int64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(i))
+}
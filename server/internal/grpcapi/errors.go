@@ -0,0 +1,59 @@
+package grpcapi
+
+import (
+	"simple_bank/server/internal/apperr"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcCodes maps error codes to gRPC status codes the way
+// apperr.Code.HTTPStatus maps them to HTTP status codes. Codes missing
+// here are Internal.
+var grpcCodes = map[apperr.Code]codes.Code{
+	apperr.CodeInvalidRequest:    codes.InvalidArgument,
+	apperr.CodeValidationFailed:  codes.InvalidArgument,
+	apperr.CodeUnauthenticated:   codes.Unauthenticated,
+	apperr.CodeForbidden:         codes.PermissionDenied,
+	apperr.CodeNotFound:          codes.NotFound,
+	apperr.CodeAccountNotFound:   codes.NotFound,
+	apperr.CodeTransferNotFound:  codes.NotFound,
+	apperr.CodeEntryNotFound:     codes.NotFound,
+	apperr.CodeWebhookNotFound:   codes.NotFound,
+	apperr.CodeDeliveryNotFound:  codes.NotFound,
+	apperr.CodeAPIKeyNotFound:    codes.NotFound,
+	apperr.CodeConflict:          codes.AlreadyExists,
+	apperr.CodeAccountFrozen:     codes.FailedPrecondition,
+	apperr.CodeInsufficientFunds: codes.FailedPrecondition,
+	apperr.CodeCurrencyMismatch:  codes.FailedPrecondition,
+	apperr.CodeResumeExpired:     codes.OutOfRange,
+}
+
+// toStatus maps service errors to gRPC statuses. Errors without an apperr
+// code are reported as Internal without their details.
+func toStatus(err error) error {
+	appErr := apperr.From(err)
+	code, ok := grpcCodes[appErr.Code]
+	if !ok {
+		return &internalError{cause: err}
+	}
+	return status.Error(code, appErr.Message)
+}
+
+// internalError is sent to clients as a bare Internal status while keeping
+// its cause for the log
+type internalError struct {
+	cause error
+}
+
+func (e *internalError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.cause
+}
+
+func (e *internalError) GRPCStatus() *status.Status {
+	return status.New(codes.Internal, "internal server error")
+}
@@ -0,0 +1,164 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/grpcapi/bankv1"
+	"simple_bank/server/internal/middleware"
+	"simple_bank/server/internal/requestinfo"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyScopes maps the methods open to API keys to the scope they require,
+// mirroring the REST routes
+var apiKeyScopes = map[string]auth.Scope{
+	bankv1.BankService_CreateAccount_FullMethodName:       auth.ScopeAccountsWrite,
+	bankv1.BankService_GetAccount_FullMethodName:          auth.ScopeAccountsRead,
+	bankv1.BankService_ListAccounts_FullMethodName:        auth.ScopeAccountsRead,
+	bankv1.BankService_ListAccountsByOwner_FullMethodName: auth.ScopeAccountsRead,
+	bankv1.BankService_UpdateAccount_FullMethodName:       auth.ScopeAccountsWrite,
+	bankv1.BankService_DeleteAccount_FullMethodName:       auth.ScopeAccountsWrite,
+	bankv1.BankService_CreateTransfer_FullMethodName:      auth.ScopeTransfersWrite,
+	bankv1.BankService_GetTransfer_FullMethodName:         auth.ScopeTransfersRead,
+	bankv1.BankService_ListTransfers_FullMethodName:       auth.ScopeTransfersRead,
+	bankv1.BankService_GetEntry_FullMethodName:            auth.ScopeAccountsRead,
+	bankv1.BankService_ListEntries_FullMethodName:         auth.ScopeAccountsRead,
+	bankv1.BankService_WatchAccount_FullMethodName:        auth.ScopeAccountsRead,
+}
+
+// interceptor does for gRPC calls what the RequestInfo, AccessLog,
+// Authenticate and RequireRouteScopes middleware do for REST requests
+type interceptor struct {
+	authenticators []auth.Authenticator
+	log            *slog.Logger
+}
+
+func (i *interceptor) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, err := i.prepare(ctx, info.FullMethod)
+	var resp any
+	if err == nil {
+		resp, err = handler(ctx, req)
+	}
+	i.logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (i *interceptor) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, err := i.prepare(ss.Context(), info.FullMethod)
+	if err == nil {
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+	i.logCall(ctx, info.FullMethod, start, err)
+	return err
+}
+
+// logCall logs a finished call. Internal errors are logged with the cause
+// that toStatus kept from the client.
+func (i *interceptor) logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []any{"method", method, "code", code.String(), "duration", time.Since(start)}
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+		attrs = append(attrs, "error", err)
+	}
+	i.log.Log(ctx, level, "call", attrs...)
+}
+
+// prepare attaches request info and the authenticated principal to ctx. The
+// returned context carries the request info even when err is set.
+func (i *interceptor) prepare(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := first(md, middleware.RequestIDHeader)
+	if !requestinfo.ValidID(requestID) {
+		requestID = requestinfo.NewID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(middleware.RequestIDHeader, requestID))
+	ctx = requestinfo.WithInfo(ctx, requestinfo.Info{
+		RequestID: requestID,
+		SourceIP:  sourceIP(ctx),
+	})
+
+	principal, err := i.authenticate(ctx, md)
+	if err != nil {
+		return ctx, err
+	}
+	if principal.IsService() {
+		scope, mapped := apiKeyScopes[method]
+		if !mapped || !principal.HasScope(scope) {
+			return ctx, status.Error(codes.PermissionDenied, "API key lacks the required scope")
+		}
+	}
+
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func (i *interceptor) authenticate(ctx context.Context, md metadata.MD) (*auth.Principal, error) {
+	// The authenticators read HTTP headers, which is what gRPC metadata
+	// travels as
+	header := http.Header{}
+	for key, values := range md {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+	r := (&http.Request{Header: header}).WithContext(ctx)
+
+	for _, authenticator := range i.authenticators {
+		principal, err := authenticator.Authenticate(r)
+		if errors.Is(err, auth.ErrNoCredentials) {
+			continue
+		}
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		return principal, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "authentication required")
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func sourceIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// serverStream overrides the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
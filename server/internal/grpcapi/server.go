@@ -0,0 +1,335 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/grpcapi/bankv1"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+	"simple_bank/server/internal/stream"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewServer returns a gRPC server exposing services through BankService.
+// It accepts the same credentials as the REST API.
+func NewServer(services *services.Services, authenticators ...auth.Authenticator) *grpc.Server {
+	i := &interceptor{authenticators: authenticators, log: logging.For("grpc")}
+	server := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(i.unary),
+		grpc.ChainStreamInterceptor(i.stream),
+	)
+	bankv1.RegisterBankServiceServer(server, &bankServer{services: services})
+	return server
+}
+
+type bankServer struct {
+	bankv1.UnimplementedBankServiceServer
+	services *services.Services
+}
+
+func (s *bankServer) CreateAccount(ctx context.Context, req *bankv1.CreateAccountRequest) (*bankv1.Account, error) {
+	if req.Currency == "" {
+		return nil, status.Error(codes.InvalidArgument, "currency is required")
+	}
+	if req.InitialBalance < 0 {
+		return nil, status.Error(codes.InvalidArgument, "initial_balance must not be negative")
+	}
+
+	account, err := s.services.Account.CreateAccount(ctx, req.Owner, req.Currency, req.Type, req.InitialBalance)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+func (s *bankServer) GetAccount(ctx context.Context, req *bankv1.GetAccountRequest) (*bankv1.Account, error) {
+	account, err := s.services.Account.GetAccount(ctx, req.Id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+func (s *bankServer) ListAccounts(ctx context.Context, req *bankv1.ListAccountsRequest) (*bankv1.ListAccountsResponse, error) {
+	accounts, err := s.services.Account.ListAccounts(ctx, services.PageRequest{
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+		Cursor:   req.Cursor,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &bankv1.ListAccountsResponse{
+		Accounts:   toAccounts(accounts.Items),
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		NextCursor: accounts.NextCursor,
+	}, nil
+}
+
+func (s *bankServer) ListAccountsByOwner(ctx context.Context, req *bankv1.ListAccountsByOwnerRequest) (*bankv1.ListAccountsResponse, error) {
+	accounts, err := s.services.Account.GetAccountsByOwner(ctx, req.Owner, services.PageRequest{
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+		Cursor:   req.Cursor,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &bankv1.ListAccountsResponse{
+		Accounts:   toAccounts(accounts.Items),
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		NextCursor: accounts.NextCursor,
+	}, nil
+}
+
+func (s *bankServer) UpdateAccount(ctx context.Context, req *bankv1.UpdateAccountRequest) (*bankv1.Account, error) {
+	if req.Balance < 0 {
+		return nil, status.Error(codes.InvalidArgument, "balance must not be negative")
+	}
+
+	account, err := s.services.Account.UpdateAccount(ctx, req.Id, req.Balance)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+func (s *bankServer) DeleteAccount(ctx context.Context, req *bankv1.DeleteAccountRequest) (*bankv1.DeleteAccountResponse, error) {
+	if err := s.services.Account.DeleteAccount(ctx, req.Id); err != nil {
+		return nil, toStatus(err)
+	}
+	return &bankv1.DeleteAccountResponse{}, nil
+}
+
+func (s *bankServer) ListDelegates(ctx context.Context, req *bankv1.ListDelegatesRequest) (*bankv1.ListDelegatesResponse, error) {
+	delegates, err := s.services.Account.ListDelegates(ctx, req.AccountId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &bankv1.ListDelegatesResponse{}
+	for i := range delegates {
+		resp.Delegates = append(resp.Delegates, toDelegate(&delegates[i]))
+	}
+	return resp, nil
+}
+
+func (s *bankServer) GrantDelegate(ctx context.Context, req *bankv1.GrantDelegateRequest) (*bankv1.Delegate, error) {
+	delegate, err := s.services.Account.GrantDelegate(ctx, req.AccountId, req.Delegate, req.CanTransfer)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toDelegate(delegate), nil
+}
+
+func (s *bankServer) RevokeDelegate(ctx context.Context, req *bankv1.RevokeDelegateRequest) (*bankv1.RevokeDelegateResponse, error) {
+	if err := s.services.Account.RevokeDelegate(ctx, req.AccountId, req.Delegate); err != nil {
+		return nil, toStatus(err)
+	}
+	return &bankv1.RevokeDelegateResponse{}, nil
+}
+
+func (s *bankServer) CreateTransfer(ctx context.Context, req *bankv1.CreateTransferRequest) (*bankv1.Transfer, error) {
+	if req.ToAccountId <= 0 || req.Amount <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "to_account_id and amount must be positive")
+	}
+
+	transfer, err := s.services.Transfer.CreateTransfer(ctx, req.FromAccountId, req.ToAccountId, req.Amount)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTransfer(transfer), nil
+}
+
+func (s *bankServer) GetTransfer(ctx context.Context, req *bankv1.GetTransferRequest) (*bankv1.Transfer, error) {
+	transfer, err := s.services.Transfer.GetTransfer(ctx, req.Id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTransfer(transfer), nil
+}
+
+func (s *bankServer) ListTransfers(ctx context.Context, req *bankv1.ListTransfersRequest) (*bankv1.ListTransfersResponse, error) {
+	transfers, err := s.services.Transfer.ListTransfers(ctx, req.AccountId, repositories.TransferFilter{}, services.PageRequest{
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+		Cursor:   req.Cursor,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &bankv1.ListTransfersResponse{Page: req.Page, PageSize: req.PageSize, NextCursor: transfers.NextCursor}
+	for i := range transfers.Items {
+		resp.Transfers = append(resp.Transfers, toTransfer(&transfers.Items[i]))
+	}
+	return resp, nil
+}
+
+func (s *bankServer) GetEntry(ctx context.Context, req *bankv1.GetEntryRequest) (*bankv1.Entry, error) {
+	entry, err := s.services.Entry.GetEntry(ctx, req.Id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toEntry(entry), nil
+}
+
+func (s *bankServer) ListEntries(ctx context.Context, req *bankv1.ListEntriesRequest) (*bankv1.ListEntriesResponse, error) {
+	entries, err := s.services.Entry.ListEntries(ctx, req.AccountId, repositories.EntryFilter{}, services.PageRequest{
+		Page:     int(req.Page),
+		PageSize: int(req.PageSize),
+		Cursor:   req.Cursor,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &bankv1.ListEntriesResponse{
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		NextCursor: entries.NextCursor,
+		Total:      entries.Total,
+	}
+	for i := range entries.Items {
+		resp.Entries = append(resp.Entries, toEntry(&entries.Items[i]))
+	}
+	return resp, nil
+}
+
+// WatchAccount sends the missed backlog and then live updates until the
+// client goes away, the server shuts down or the client falls behind
+func (s *bankServer) WatchAccount(req *bankv1.WatchAccountRequest, srv grpc.ServerStreamingServer[bankv1.AccountEvent]) error {
+	ctx := srv.Context()
+	lastEventID := req.LastEventId
+
+	backlog, sub, err := s.services.Stream.SubscribeAccount(ctx, req.AccountId, lastEventID)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer sub.Close()
+
+	for _, msg := range backlog {
+		if err := srv.Send(toAccountEvent(msg)); err != nil {
+			return err
+		}
+		lastEventID = msg.ID
+	}
+
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client fell behind; resume with last_event_id")
+			}
+			// Already sent in the backlog or seen before reconnecting
+			if msg.ID <= lastEventID {
+				continue
+			}
+			if err := srv.Send(toAccountEvent(msg)); err != nil {
+				return err
+			}
+			lastEventID = msg.ID
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func toAccount(account *models.Account) *bankv1.Account {
+	return &bankv1.Account{
+		Id:        account.ID,
+		Owner:     account.Owner,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		Status:    account.Status,
+		CreatedAt: timestamppb.New(account.CreatedAt),
+		Type:      account.Type,
+	}
+}
+
+func toAccounts(accounts []models.Account) []*bankv1.Account {
+	out := make([]*bankv1.Account, len(accounts))
+	for i := range accounts {
+		out[i] = toAccount(&accounts[i])
+	}
+	return out
+}
+
+func toTransfer(transfer *models.Transfer) *bankv1.Transfer {
+	return &bankv1.Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.FromAccountID,
+		ToAccountId:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+		CreatedAt:     timestamppb.New(transfer.CreatedAt),
+	}
+}
+
+func toDelegate(delegate *models.AccountDelegate) *bankv1.Delegate {
+	return &bankv1.Delegate{
+		AccountId:   delegate.AccountID,
+		Delegate:    delegate.Delegate,
+		CanTransfer: delegate.CanTransfer,
+		CreatedAt:   timestamppb.New(delegate.CreatedAt),
+	}
+}
+
+func toEntry(entry *models.Entry) *bankv1.Entry {
+	pb := &bankv1.Entry{
+		Id:        entry.ID,
+		AccountId: entry.AccountID,
+		Amount:    entry.Amount,
+		CreatedAt: timestamppb.New(entry.CreatedAt),
+	}
+	if entry.TransferID != nil {
+		pb.TransferId = *entry.TransferID
+	}
+	if entry.RunningBalance != nil {
+		pb.RunningBalance = *entry.RunningBalance
+	}
+	return pb
+}
+
+// toAccountEvent decodes the JSON data of a stream message into its typed
+// protobuf form
+func toAccountEvent(msg stream.Message) *bankv1.AccountEvent {
+	event := &bankv1.AccountEvent{Id: msg.ID, AccountId: msg.AccountID}
+
+	switch msg.Event {
+	case stream.EventEntry:
+		var entry models.Entry
+		_ = json.Unmarshal(msg.Data, &entry)
+		event.Update = &bankv1.AccountEvent_Entry{Entry: toEntry(&entry)}
+	case stream.EventBalance:
+		var balance bankv1.BalanceUpdate
+		_ = json.Unmarshal(msg.Data, &balance)
+		event.Update = &bankv1.AccountEvent_Balance{Balance: &balance}
+	case stream.EventStatus:
+		var update struct {
+			Status string `json:"status"`
+			Reason string `json:"reason"`
+		}
+		_ = json.Unmarshal(msg.Data, &update)
+		// Closing an account records no status of its own
+		if update.Status == "" {
+			update.Status = "closed"
+		}
+		event.Update = &bankv1.AccountEvent_Status{Status: &bankv1.StatusUpdate{
+			Status: update.Status,
+			Reason: update.Reason,
+		}}
+	}
+	return event
+}
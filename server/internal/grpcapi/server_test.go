@@ -0,0 +1,107 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/grpcapi"
+	"simple_bank/server/internal/grpcapi/bankv1"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// keyAuthenticator accepts any X-API-Key and grants it the scopes in the
+// key itself, so tests can pick what the caller holds
+type keyAuthenticator struct{}
+
+func (keyAuthenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	key := r.Header.Get(auth.APIKeyHeader)
+	if key == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	return &auth.Principal{Subject: "svc", APIKeyID: 1, Scopes: []auth.Scope{auth.Scope(key)}}, nil
+}
+
+func newClient(t *testing.T) bankv1.BankServiceClient {
+	t.Helper()
+	return newClientFor(t, &services.Services{})
+}
+
+// newClientFor serves svc to the client it returns
+func newClientFor(t *testing.T, svc *services.Services) bankv1.BankServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpcapi.NewServer(svc, keyAuthenticator{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return bankv1.NewBankServiceClient(conn)
+}
+
+func TestRejectsMissingCredentials(t *testing.T) {
+	client := newClient(t)
+
+	_, err := client.GetAccount(context.Background(), &bankv1.GetAccountRequest{Id: 1})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v, want Unauthenticated", err)
+	}
+}
+
+func TestRejectsAPIKeyWithoutScope(t *testing.T) {
+	client := newClient(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", string(auth.ScopeAccountsRead))
+
+	_, err := client.CreateTransfer(ctx, &bankv1.CreateTransferRequest{FromAccountId: 1, ToAccountId: 2, Amount: 5})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("transfer with accounts:read key: got %v, want PermissionDenied", err)
+	}
+
+	// Delegation is closed to API keys altogether
+	_, err = client.ListDelegates(ctx, &bankv1.ListDelegatesRequest{AccountId: 1})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("list delegates with API key: got %v, want PermissionDenied", err)
+	}
+}
+
+// openedAccounts opens accounts of the requested type
+type openedAccounts struct {
+	services.AccountService
+}
+
+func (openedAccounts) CreateAccount(ctx context.Context, owner, currency, accountType string, initialBalance int64) (*models.Account, error) {
+	return &models.Account{ID: 1, Owner: owner, Currency: currency, Type: accountType, Balance: initialBalance}, nil
+}
+
+func TestCreateAccountKeepsTheType(t *testing.T) {
+	client := newClientFor(t, &services.Services{Account: openedAccounts{}})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", string(auth.ScopeAccountsWrite))
+
+	account, err := client.CreateAccount(ctx, &bankv1.CreateAccountRequest{Owner: "alice", Currency: "USD", Type: models.AccountTypeSavings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Type != models.AccountTypeSavings {
+		t.Errorf("got a %q account, want %q", account.Type, models.AccountTypeSavings)
+	}
+}
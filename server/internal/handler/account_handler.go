@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"simple_bank/server/internal/apperr"
 	"simple_bank/server/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -34,18 +35,31 @@ func NewServicesHandler(router *gin.RouterGroup, services *services.Services) {
 		// Transfer routes (use different param name)
 		accounts.POST("/:id/transfer", handler.CreateTransfer)
 		accounts.GET("/:id/transfers", handler.ListTransfers)
+		accounts.GET("/:id/entries", handler.ListEntries)
+		accounts.GET("/:id/events", handler.StreamAccountEvents)
+
+		// Delegation routes
+		accounts.GET("/:id/delegates", handler.ListDelegates)
+		accounts.PUT("/:id/delegates/:delegate", handler.GrantDelegate)
+		accounts.DELETE("/:id/delegates/:delegate", handler.RevokeDelegate)
 	}
 
 	transfers := router.Group("/transfers")
 	{
 		transfers.GET("/:transfer_id", handler.GetTransfer) // Changed from :id to :transfer_id
 	}
+
+	entries := router.Group("/entries")
+	{
+		entries.GET("/:id", handler.GetEntry)
+	}
 }
 
 // Request/Response structures
 type CreateAccountRequest struct {
-	Owner          string `json:"owner" binding:"required"`
+	Owner          string `json:"owner"` // Defaults to the caller
 	Currency       string `json:"currency" binding:"required"`
+	Type           string `json:"type"` // Defaults to checking
 	InitialBalance int64  `json:"initial_balance" binding:"min=0"`
 }
 
@@ -54,6 +68,7 @@ type CreateAccountResponse struct {
 	Owner     string `json:"owner"`
 	Balance   int64  `json:"balance"`
 	Currency  string `json:"currency"`
+	Type      string `json:"type"`
 	CreatedAt string `json:"created_at"`
 }
 
@@ -66,18 +81,29 @@ type CreateTransferRequest struct {
 	Amount      int64 `json:"amount" binding:"required,gt=0"`
 }
 
+type GrantDelegateRequest struct {
+	CanTransfer bool `json:"can_transfer"`
+}
+
+// invalidRequest reports a request the handler could not parse. Service
+// errors are passed to c.Error as they are; middleware.RenderErrors turns
+// both into problem responses.
+func invalidRequest(c *gin.Context, message string) {
+	_ = c.Error(apperr.New(apperr.CodeInvalidRequest, message))
+}
+
 // Handler methods
 func (h *ServicesHandler) CreateAccount(c *gin.Context) {
 	var req CreateAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidRequest(c, err.Error())
 		return
 	}
 
 	account, err := h.services.Account.CreateAccount(c.Request.Context(),
-		req.Owner, req.Currency, req.InitialBalance)
+		req.Owner, req.Currency, req.Type, req.InitialBalance)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -86,6 +112,7 @@ func (h *ServicesHandler) CreateAccount(c *gin.Context) {
 		Owner:     account.Owner,
 		Balance:   account.Balance,
 		Currency:  account.Currency,
+		Type:      account.Type,
 		CreatedAt: account.CreatedAt.Format("2006-01-02 15:04:05"),
 	})
 }
@@ -93,13 +120,13 @@ func (h *ServicesHandler) CreateAccount(c *gin.Context) {
 func (h *ServicesHandler) GetAccount(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		invalidRequest(c, "Invalid account ID")
 		return
 	}
 
 	account, err := h.services.Account.GetAccount(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -107,57 +134,57 @@ func (h *ServicesHandler) GetAccount(c *gin.Context) {
 }
 
 func (h *ServicesHandler) ListAccounts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	req := pageRequest(c)
 
-	accounts, err := h.services.Account.ListAccounts(c.Request.Context(), page, pageSize)
+	accounts, err := h.services.Account.ListAccounts(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"accounts":  accounts,
-		"page":      page,
-		"page_size": pageSize,
+		"accounts":    accounts.Items,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"next_cursor": accounts.NextCursor,
 	})
 }
 
 func (h *ServicesHandler) GetAccountsByOwner(c *gin.Context) {
 	owner := c.Param("owner")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	req := pageRequest(c)
 
-	accounts, err := h.services.Account.GetAccountsByOwner(c.Request.Context(), owner, page, pageSize)
+	accounts, err := h.services.Account.GetAccountsByOwner(c.Request.Context(), owner, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"accounts":  accounts,
-		"owner":     owner,
-		"page":      page,
-		"page_size": pageSize,
+		"accounts":    accounts.Items,
+		"owner":       owner,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"next_cursor": accounts.NextCursor,
 	})
 }
 
 func (h *ServicesHandler) UpdateAccount(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		invalidRequest(c, "Invalid account ID")
 		return
 	}
 
 	var req UpdateAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidRequest(c, err.Error())
 		return
 	}
 
 	account, err := h.services.Account.UpdateAccount(c.Request.Context(), id, req.Balance)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -167,13 +194,13 @@ func (h *ServicesHandler) UpdateAccount(c *gin.Context) {
 func (h *ServicesHandler) DeleteAccount(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		invalidRequest(c, "Invalid account ID")
 		return
 	}
 
 	err = h.services.Account.DeleteAccount(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -183,19 +210,19 @@ func (h *ServicesHandler) DeleteAccount(c *gin.Context) {
 func (h *ServicesHandler) CreateTransfer(c *gin.Context) {
 	var req CreateTransferRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidRequest(c, err.Error())
 		return
 	}
 
-	fromAccountID, err := strconv.ParseInt(c.Param("from_account_id"), 10, 64)
+	fromAccountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		invalidRequest(c, "Invalid account ID")
 		return
 	}
 
 	transfer, err := h.services.Transfer.CreateTransfer(c.Request.Context(), fromAccountID, req.ToAccountID, req.Amount)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
@@ -203,15 +230,15 @@ func (h *ServicesHandler) CreateTransfer(c *gin.Context) {
 }
 
 func (h *ServicesHandler) GetTransfer(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := strconv.ParseInt(c.Param("transfer_id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		invalidRequest(c, "Invalid transfer ID")
 		return
 	}
 
 	transfer, err := h.services.Transfer.GetTransfer(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		_ = c.Error(err)
 		return
 	}
 
@@ -219,24 +246,129 @@ func (h *ServicesHandler) GetTransfer(c *gin.Context) {
 }
 
 func (h *ServicesHandler) ListTransfers(c *gin.Context) {
-	accountID, err := strconv.ParseInt(c.Param("account_id"), 10, 64)
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		invalidRequest(c, "Invalid account ID")
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	var query TransferQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+	req := pageRequest(c)
+
+	transfers, err := h.services.Transfer.ListTransfers(c.Request.Context(), accountID, query.filter(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transfers":   transfers.Items,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"next_cursor": transfers.NextCursor,
+		"total":       transfers.Total,
+	})
+}
+
+func (h *ServicesHandler) GetEntry(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid entry ID")
+		return
+	}
+
+	entry, err := h.services.Entry.GetEntry(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *ServicesHandler) ListEntries(c *gin.Context) {
+	accountID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	var query EntryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+	req := pageRequest(c)
 
-	transfers, err := h.services.Transfer.ListTransfers(c.Request.Context(), accountID, page, pageSize)
+	entries, err := h.services.Entry.ListEntries(c.Request.Context(), accountID, query.filter(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"transfers": transfers,
-		"page":      page,
-		"page_size": pageSize,
+		"entries":     entries.Items,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"next_cursor": entries.NextCursor,
+		"total":       entries.Total,
 	})
 }
+
+func (h *ServicesHandler) ListDelegates(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	delegates, err := h.services.Account.ListDelegates(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delegates": delegates})
+}
+
+func (h *ServicesHandler) GrantDelegate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	var req GrantDelegateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	delegate, err := h.services.Account.GrantDelegate(c.Request.Context(), id, c.Param("delegate"), req.CanTransfer)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delegate)
+}
+
+func (h *ServicesHandler) RevokeDelegate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	err = h.services.Account.RevokeDelegate(c.Request.Context(), id, c.Param("delegate"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Delegate revoked successfully"})
+}
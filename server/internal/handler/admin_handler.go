@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	services *services.Services
+}
+
+func NewAdminHandler(router *gin.RouterGroup, services *services.Services) {
+
+	handler := &AdminHandler{
+		services: services,
+	}
+
+	accounts := router.Group("/accounts")
+	{
+		accounts.GET("", handler.ListAccounts)
+		accounts.POST("/:id/freeze", handler.FreezeAccount)
+		accounts.POST("/:id/unfreeze", handler.UnfreezeAccount)
+		accounts.POST("/:id/adjustments", handler.AdjustBalance)
+	}
+
+	transfers := router.Group("/transfers")
+	{
+		transfers.GET("", handler.ListTransfers)
+		transfers.GET("/:transfer_id", handler.GetTransfer)
+	}
+
+	deliveries := router.Group("/webhooks/deliveries")
+	{
+		deliveries.GET("", handler.ListDeliveries)
+		deliveries.POST("/:id/replay", handler.ReplayDelivery)
+	}
+
+	router.GET("/actions", handler.ListActions)
+	router.GET("/audit-logs", handler.ListAuditLogs)
+}
+
+type AccountStatusRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type AdjustBalanceRequest struct {
+	Amount int64  `json:"amount" binding:"required"` // Negative to debit
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (h *AdminHandler) ListAccounts(c *gin.Context) {
+	req := pageRequest(c)
+
+	accounts, err := h.services.Admin.ListAccounts(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts":    accounts.Items,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"next_cursor": accounts.NextCursor,
+	})
+}
+
+func (h *AdminHandler) FreezeAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	var req AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	account, err := h.services.Admin.FreezeAccount(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *AdminHandler) UnfreezeAccount(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	var req AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	account, err := h.services.Admin.UnfreezeAccount(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *AdminHandler) AdjustBalance(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	var req AdjustBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	account, err := h.services.Admin.AdjustBalance(c.Request.Context(), id, req.Amount, req.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *AdminHandler) GetTransfer(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("transfer_id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid transfer ID")
+		return
+	}
+
+	transfer, err := h.services.Admin.GetTransfer(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+func (h *AdminHandler) ListTransfers(c *gin.Context) {
+	req := pageRequest(c)
+
+	transfers, err := h.services.Admin.ListTransfers(c.Request.Context(), req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transfers":   transfers.Items,
+		"page":        req.Page,
+		"page_size":   req.PageSize,
+		"next_cursor": transfers.NextCursor,
+	})
+}
+
+func (h *AdminHandler) ListActions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	actions, err := h.services.Admin.ListActions(c.Request.Context(), page, pageSize)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"actions":   actions,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (h *AdminHandler) ListAuditLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	targetID, _ := strconv.ParseInt(c.Query("target_id"), 10, 64)
+
+	filter := repositories.AuditLogFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   targetID,
+		RequestID:  c.Query("request_id"),
+	}
+
+	logs, err := h.services.Admin.ListAuditLogs(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit_logs": logs,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+func (h *AdminHandler) ListDeliveries(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	deliveries, err := h.services.Webhook.ListDeliveries(c.Request.Context(), c.Query("status"), page, pageSize)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+func (h *AdminHandler) ReplayDelivery(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.services.Webhook.ReplayDelivery(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
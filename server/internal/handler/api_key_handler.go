@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	services *services.Services
+}
+
+func NewAPIKeyHandler(router *gin.RouterGroup, services *services.Services) {
+
+	handler := &APIKeyHandler{
+		services: services,
+	}
+
+	keys := router.Group("/api-keys")
+	{
+		keys.POST("", handler.CreateAPIKey)
+		keys.GET("", handler.ListAPIKeys)
+		keys.DELETE("/:id", handler.RevokeAPIKey)
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name       string       `json:"name" binding:"required"`
+	Scopes     []auth.Scope `json:"scopes" binding:"required,min=1"`
+	AccountIDs []int64      `json:"account_ids"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+}
+
+type CreateAPIKeyResponse struct {
+	*models.APIKey
+	// Key is the plaintext key, shown only once
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	key, rawKey, err := h.services.APIKey.CreateAPIKey(c.Request.Context(),
+		req.Name, req.Scopes, req.AccountIDs, req.ExpiresAt)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKey: key, Key: rawKey})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	keys, err := h.services.APIKey.ListAPIKeys(c.Request.Context(), page, pageSize)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys":  keys,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid API key ID")
+		return
+	}
+
+	err = h.services.APIKey.RevokeAPIKey(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"simple_bank/server/internal/openapi"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler serves the OpenAPI document and a Swagger UI reading it.
+// Register it on a group without authentication so the UI can load.
+func NewDocsHandler(router *gin.RouterGroup, doc *openapi3.T) {
+	spec, err := openapi.JSON(doc)
+	if err != nil {
+		panic(err)
+	}
+
+	handler := &DocsHandler{
+		spec: spec,
+	}
+
+	router.GET("/openapi.json", handler.Spec)
+
+	prefix := router.BasePath() + "/docs"
+	ui := http.StripPrefix(prefix, openapi.SwaggerUI(router.BasePath()+"/openapi.json"))
+	router.GET("/docs", func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, prefix+"/")
+	})
+	router.GET("/docs/*filepath", gin.WrapH(ui))
+}
+
+func (h *DocsHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"simple_bank/server/internal/graphapi"
+	"simple_bank/server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLHandler struct {
+	server *graphapi.Server
+}
+
+func NewGraphQLHandler(router *gin.RouterGroup, services *services.Services) {
+
+	handler := &GraphQLHandler{
+		server: graphapi.NewServer(services),
+	}
+
+	router.POST("/graphql", handler.Query)
+}
+
+// Query executes a GraphQL request. Errors in the query itself are
+// reported in the response body with status 200, as GraphQL clients expect.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphapi.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, h.server.Execute(c.Request.Context(), req))
+}
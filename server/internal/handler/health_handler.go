@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"simple_bank/server/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler serves the liveness and readiness probes. /health is
+// kept for existing monitors and reports readiness.
+func NewHealthHandler(router gin.IRoutes, checker *health.Checker) {
+	handler := &HealthHandler{
+		checker: checker,
+	}
+
+	router.GET("/livez", handler.Live)
+	router.GET("/readyz", handler.Ready)
+	router.GET("/health", handler.Ready)
+}
+
+func (h *HealthHandler) Live(c *gin.Context) {
+	respondHealth(c, h.checker.Live(c.Request.Context()))
+}
+
+func (h *HealthHandler) Ready(c *gin.Context) {
+	respondHealth(c, h.checker.Ready(c.Request.Context()))
+}
+
+func respondHealth(c *gin.Context, report health.Report) {
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageRequest reads the page, page_size, cursor, sort and order query
+// parameters
+func pageRequest(c *gin.Context) services.PageRequest {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	return services.PageRequest{
+		Page:      page,
+		PageSize:  pageSize,
+		Cursor:    c.Query("cursor"),
+		Sort:      repositories.SortField(c.Query("sort")),
+		Ascending: c.Query("order") == "asc",
+	}
+}
+
+// TransferQuery holds the filters of a transfer listing. From and To bound
+// the creation time to [from, to).
+type TransferQuery struct {
+	From           time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To             time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinAmount      *int64    `form:"min_amount"`
+	MaxAmount      *int64    `form:"max_amount"`
+	Direction      string    `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+	CounterpartyID int64     `form:"counterparty_id"`
+	Currency       string    `form:"currency"`
+}
+
+func (q TransferQuery) filter() repositories.TransferFilter {
+	return repositories.TransferFilter{
+		CreatedFrom:    q.From,
+		CreatedTo:      q.To,
+		MinAmount:      q.MinAmount,
+		MaxAmount:      q.MaxAmount,
+		Direction:      repositories.Direction(q.Direction),
+		CounterpartyID: q.CounterpartyID,
+		Currency:       q.Currency,
+	}
+}
+
+// EntryQuery holds the filters of an entry listing. Amounts bound the size
+// of an entry whatever its sign; direction tells credits from debits.
+type EntryQuery struct {
+	From           time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To             time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinAmount      *int64    `form:"min_amount"`
+	MaxAmount      *int64    `form:"max_amount"`
+	Direction      string    `form:"direction" binding:"omitempty,oneof=incoming outgoing"`
+	CounterpartyID int64     `form:"counterparty_id"`
+	Type           string    `form:"type" binding:"omitempty,oneof=transfer adjustment"`
+}
+
+func (q EntryQuery) filter() repositories.EntryFilter {
+	return repositories.EntryFilter{
+		CreatedFrom:    q.From,
+		CreatedTo:      q.To,
+		MinAmount:      q.MinAmount,
+		MaxAmount:      q.MaxAmount,
+		Direction:      repositories.Direction(q.Direction),
+		CounterpartyID: q.CounterpartyID,
+		Type:           repositories.EntryType(q.Type),
+	}
+}
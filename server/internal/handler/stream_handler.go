@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment so proxies
+// do not close the connection
+const streamKeepAlive = 15 * time.Second
+
+// StreamAccountEvents serves live entries, balance and status changes of an
+// account as server-sent events. Clients resume after a disconnect with the
+// Last-Event-ID header (or last_event_id query parameter for EventSource
+// polyfills that cannot set headers).
+func (h *ServicesHandler) StreamAccountEvents(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid account ID")
+		return
+	}
+
+	lastEventIDParam := c.GetHeader("Last-Event-ID")
+	if lastEventIDParam == "" {
+		lastEventIDParam = c.Query("last_event_id")
+	}
+	lastEventID, _ := strconv.ParseInt(lastEventIDParam, 10, 64)
+
+	backlog, sub, err := h.services.Stream.SubscribeAccount(c.Request.Context(), id, lastEventID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer sub.Close()
+
+	// The server's write timeout would cut long-lived streams short
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, msg := range backlog {
+		writeEvent(c.Writer, msg.ID, msg.Event, msg.Data)
+		lastEventID = msg.ID
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				// Fell too far behind; the client reconnects and resumes
+				return false
+			}
+			// Already sent in the backlog or seen before reconnecting
+			if msg.ID <= lastEventID {
+				return true
+			}
+			writeEvent(w, msg.ID, msg.Event, msg.Data)
+			lastEventID = msg.ID
+			return true
+		case <-keepAlive.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+func writeEvent(w io.Writer, id int64, event string, data []byte) {
+	_ = sse.Encode(w, sse.Event{
+		Id:    strconv.FormatInt(id, 10),
+		Event: event,
+		Data:  data,
+	})
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	services *services.Services
+}
+
+func NewWebhookHandler(router *gin.RouterGroup, services *services.Services) {
+
+	handler := &WebhookHandler{
+		services: services,
+	}
+
+	webhooks := router.Group("/webhooks")
+	{
+		webhooks.POST("", handler.Subscribe)
+		webhooks.GET("", handler.ListSubscriptions)
+		webhooks.DELETE("/:id", handler.Unsubscribe)
+	}
+}
+
+type SubscribeWebhookRequest struct {
+	AccountID        int64    `json:"account_id" binding:"required,gt=0"`
+	URL              string   `json:"url" binding:"required"`
+	EventTypes       []string `json:"event_types" binding:"required,min=1"`
+	BalanceThreshold *int64   `json:"balance_threshold"`
+}
+
+type SubscribeWebhookResponse struct {
+	*models.WebhookSubscription
+	// Secret signs deliveries and is shown only once
+	Secret string `json:"secret"`
+}
+
+func (h *WebhookHandler) Subscribe(c *gin.Context) {
+	var req SubscribeWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	subscription, secret, err := h.services.Webhook.Subscribe(c.Request.Context(),
+		req.AccountID, req.URL, req.EventTypes, req.BalanceThreshold)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, SubscribeWebhookResponse{WebhookSubscription: subscription, Secret: secret})
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.services.Webhook.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions})
+}
+
+func (h *WebhookHandler) Unsubscribe(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidRequest(c, "Invalid webhook ID")
+		return
+	}
+
+	err = h.services.Webhook.Unsubscribe(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
@@ -0,0 +1,106 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Database checks that a connection to db can be used
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, db.PingContext(ctx)
+	}
+}
+
+// Migrations checks that the schema has been migrated to at least version
+// and that no migration was left half applied. Newer schemas are accepted
+// so that replicas of the previous release stay ready during a rollout.
+func Migrations(db *sql.DB, version uint) Check {
+	return func(ctx context.Context) (map[string]any, error) {
+		var current uint
+		var dirty bool
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+		if err != nil {
+			return nil, fmt.Errorf("read schema version: %w", err)
+		}
+
+		details := map[string]any{"version": current, "required": version}
+		if dirty {
+			return details, fmt.Errorf("migration %d did not complete", current)
+		}
+		if current < version {
+			return details, fmt.Errorf("schema is at version %d, want %d", current, version)
+		}
+		return details, nil
+	}
+}
+
+// Pool checks that less than maxSaturation of db's connections are in use,
+// so that a replica whose pool is exhausted stops taking new requests
+func Pool(db *sql.DB, maxSaturation float64) Check {
+	return func(context.Context) (map[string]any, error) {
+		stats := db.Stats()
+		details := map[string]any{
+			"in_use":     stats.InUse,
+			"idle":       stats.Idle,
+			"max_open":   stats.MaxOpenConnections,
+			"wait_count": stats.WaitCount,
+		}
+		if stats.MaxOpenConnections == 0 {
+			return details, nil
+		}
+
+		saturation := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		details["saturation"] = saturation
+		if saturation >= maxSaturation {
+			return details, fmt.Errorf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+		}
+		return details, nil
+	}
+}
+
+// Heartbeat records that a background worker is making progress. The zero
+// value has never beaten.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+// Beat records progress now
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns when Beat was last called, or the zero time
+func (h *Heartbeat) Last() time.Time {
+	last := h.last.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+// Heartbeats checks that every worker in workers beat within maxAge
+func Heartbeats(maxAge time.Duration, workers map[string]*Heartbeat) Check {
+	return func(context.Context) (map[string]any, error) {
+		details := make(map[string]any, len(workers))
+		var err error
+		for name, heartbeat := range workers {
+			last := heartbeat.Last()
+			if last.IsZero() {
+				details[name] = nil
+				err = fmt.Errorf("%s has not started", name)
+				continue
+			}
+
+			age := time.Since(last)
+			details[name] = age.Round(time.Millisecond).String()
+			if age > maxAge {
+				err = fmt.Errorf("%s last made progress %s ago", name, age.Round(time.Second))
+			}
+		}
+		return details, err
+	}
+}
@@ -0,0 +1,124 @@
+// Package health reports whether the server is alive and ready for
+// traffic. Liveness covers the process itself and its background workers;
+// readiness also covers the database and fails once shutdown begins, so
+// load balancers stop routing to a replica before it stops serving.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status of a component or of a whole report
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// ErrShuttingDown fails readiness once Drain has been called
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Check inspects one component. It returns details worth reporting, such
+// as a version or pool size, and an error when the component is unhealthy.
+type Check func(ctx context.Context) (map[string]any, error)
+
+// Component is the outcome of one check
+type Component struct {
+	Status   Status         `json:"status"`
+	Error    string         `json:"error,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+	Duration string         `json:"duration"`
+}
+
+// Report is the outcome of every check of a probe. It is up only when all
+// of its components are.
+type Report struct {
+	Status     Status               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Checker runs the liveness and readiness checks
+type Checker struct {
+	timeout   time.Duration
+	liveness  map[string]Check
+	readiness map[string]Check
+	draining  atomic.Bool
+}
+
+// NewChecker returns a checker giving each check up to timeout
+func NewChecker(timeout time.Duration) *Checker {
+	c := &Checker{
+		timeout:   timeout,
+		liveness:  make(map[string]Check),
+		readiness: make(map[string]Check),
+	}
+	c.AddReadiness("shutdown", func(context.Context) (map[string]any, error) {
+		if c.draining.Load() {
+			return nil, ErrShuttingDown
+		}
+		return nil, nil
+	})
+	return c
+}
+
+// AddLiveness registers a check whose failure means the process should be
+// restarted. Register checks before serving probes.
+func (c *Checker) AddLiveness(name string, check Check) {
+	c.liveness[name] = check
+}
+
+// AddReadiness registers a check whose failure means the server should not
+// be sent traffic for now
+func (c *Checker) AddReadiness(name string, check Check) {
+	c.readiness[name] = check
+}
+
+// Drain makes readiness fail from now on
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Live runs the liveness checks
+func (c *Checker) Live(ctx context.Context) Report {
+	return c.run(ctx, c.liveness)
+}
+
+// Ready runs the readiness checks
+func (c *Checker) Ready(ctx context.Context) Report {
+	return c.run(ctx, c.readiness)
+}
+
+// run runs checks concurrently, each bounded by the timeout
+func (c *Checker) run(ctx context.Context, checks map[string]Check) Report {
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check(ctx)
+			component := Component{Status: StatusUp, Details: details, Duration: time.Since(start).String()}
+			if err != nil {
+				component.Status = StatusDown
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = component
+			if err != nil {
+				report.Status = StatusDown
+			}
+		})
+	}
+	wg.Wait()
+	return report
+}
@@ -0,0 +1,55 @@
+package health_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple_bank/server/internal/health"
+)
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.AddReadiness("database", func(context.Context) (map[string]any, error) {
+		return map[string]any{"version": 12}, nil
+	})
+
+	if report := checker.Ready(context.Background()); report.Status != health.StatusUp {
+		t.Fatalf("got %+v before draining, want up", report)
+	}
+	checker.Drain()
+	report := checker.Ready(context.Background())
+	if report.Status != health.StatusDown || report.Components["shutdown"].Status != health.StatusDown {
+		t.Fatalf("got %+v while draining, want the shutdown component down", report)
+	}
+	if report.Components["database"].Status != health.StatusUp {
+		t.Errorf("draining failed the database component: %+v", report.Components["database"])
+	}
+}
+
+func TestChecksAreBoundedByTheTimeout(t *testing.T) {
+	checker := health.NewChecker(10 * time.Millisecond)
+	checker.AddLiveness("stuck", func(ctx context.Context) (map[string]any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	report := checker.Live(context.Background())
+	if component := report.Components["stuck"]; component.Status != health.StatusDown || component.Error == "" {
+		t.Fatalf("got %+v, want the stuck check down", component)
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	var beating, idle health.Heartbeat
+	beating.Beat()
+	check := health.Heartbeats(time.Minute, map[string]*health.Heartbeat{"relay": &beating})
+	if _, err := check(context.Background()); err != nil {
+		t.Errorf("a fresh heartbeat failed: %v", err)
+	}
+
+	check = health.Heartbeats(time.Minute, map[string]*health.Heartbeat{"relay": &beating, "dispatcher": &idle})
+	if _, err := check(context.Background()); err == nil {
+		t.Error("a worker that never beat passed")
+	}
+}
@@ -0,0 +1,117 @@
+// Package logging configures the structured JSON logger shared by the
+// server. Records carry the request ID and trace of the context they are
+// logged with, sensitive attributes are redacted, and each component can be
+// given its own level.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"simple_bank/server/internal/requestinfo"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ComponentKey is the attribute naming the part of the server a record
+// comes from. Loggers returned by For set it.
+const ComponentKey = "component"
+
+// Levels holds the default minimum level and per-component overrides
+type Levels struct {
+	Default    slog.Level
+	Components map[string]slog.Level
+}
+
+// ParseLevels parses a default level such as "info" and overrides written
+// as "gorm=warn,http=debug"
+func ParseLevels(defaultLevel, overrides string) (Levels, error) {
+	levels := Levels{Components: make(map[string]slog.Level)}
+	if err := levels.Default.UnmarshalText([]byte(defaultLevel)); err != nil {
+		return Levels{}, fmt.Errorf("log level %q: %w", defaultLevel, err)
+	}
+
+	for override := range strings.SplitSeq(overrides, ",") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+		component, level, ok := strings.Cut(override, "=")
+		if !ok {
+			return Levels{}, fmt.Errorf("log level override %q is not component=level", override)
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return Levels{}, fmt.Errorf("log level for %s: %w", component, err)
+		}
+		levels.Components[component] = l
+	}
+	return levels, nil
+}
+
+// For returns the level records of component are logged at
+func (l Levels) For(component string) slog.Level {
+	if level, ok := l.Components[component]; ok {
+		return level
+	}
+	return l.Default
+}
+
+// New returns a logger writing JSON lines to w. Customer names are
+// pseudonymized with an HMAC under pseudonymKey.
+func New(w io.Writer, levels Levels, pseudonymKey []byte) *slog.Logger {
+	// Component levels below the default must get past the JSON handler
+	lowest := levels.Default
+	for _, level := range levels.Components {
+		lowest = min(lowest, level)
+	}
+	json := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       lowest,
+		ReplaceAttr: newRedactor(pseudonymKey).replaceAttr,
+	})
+	return slog.New(&handler{next: json, levels: levels, level: levels.Default})
+}
+
+// For returns the default logger tagged with component, so that its
+// records are filtered by the component's level
+func For(component string) *slog.Logger {
+	return slog.Default().With(ComponentKey, component)
+}
+
+// handler applies component levels and adds the request ID to records
+type handler struct {
+	next   slog.Handler
+	levels Levels
+	level  slog.Level
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestinfo.FromContext(ctx).RequestID; id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	level := h.level
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey {
+			level = h.levels.For(attr.Value.String())
+		}
+	}
+	return &handler{next: h.next.WithAttrs(attrs), levels: h.levels, level: level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), levels: h.levels, level: h.level}
+}
@@ -0,0 +1,69 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/requestinfo"
+)
+
+func TestComponentLevels(t *testing.T) {
+	levels, err := logging.ParseLevels("info", "gorm=warn, changefeed=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	logger := logging.New(&buf, levels, nil)
+
+	logger.With(logging.ComponentKey, "gorm").Info("hidden")
+	logger.With(logging.ComponentKey, "changefeed").Debug("shown")
+	logger.Debug("hidden")
+	logger.Info("shown")
+
+	if got := strings.Count(buf.String(), "shown"); got != 2 || strings.Contains(buf.String(), "hidden") {
+		t.Fatalf("unexpected records:\n%s", buf.String())
+	}
+}
+
+func TestParseLevelsRejectsUnknownLevels(t *testing.T) {
+	for _, overrides := range []string{"gorm", "gorm=loud"} {
+		if _, err := logging.ParseLevels("info", overrides); err == nil {
+			t.Errorf("overrides %q: expected an error", overrides)
+		}
+	}
+}
+
+func TestRecordsAreRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.Levels{}, []byte("pseudonym key"))
+	ctx := requestinfo.WithInfo(context.Background(), requestinfo.Info{RequestID: "req-1"})
+
+	logger.InfoContext(ctx, "opened", "owner", "alice", "secret", "hunter2",
+		slog.Any("payload", logging.RedactJSON([]byte(`{"account": {"owner": "alice", "balance": 5}}`))))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["request_id"] != "req-1" {
+		t.Errorf("got request ID %v, want req-1", record["request_id"])
+	}
+	if strings.Contains(buf.String(), "alice") || strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("record leaks owner or secret: %s", buf.String())
+	}
+	if record["owner"] != record["payload"].(map[string]any)["account"].(map[string]any)["owner"] {
+		t.Errorf("owner pseudonyms differ: %s", buf.String())
+	}
+
+	// The pseudonym depends on the key, so it cannot be found by hashing names
+	var other bytes.Buffer
+	logging.New(&other, logging.Levels{}, []byte("another key")).Info("opened", "owner", "alice")
+	if strings.Contains(other.String(), record["owner"].(string)) {
+		t.Errorf("pseudonym %v does not depend on the key", record["owner"])
+	}
+}
@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// piiKeys name attributes holding customer names. They are replaced by a
+// keyed hash, so records about the same customer can still be correlated
+// but names cannot be recovered by hashing likely candidates.
+var piiKeys = map[string]bool{
+	"owner":    true,
+	"subject":  true,
+	"delegate": true,
+	"actor":    true,
+}
+
+// secretKeys name attributes that are never logged
+var secretKeys = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+	"api_key":       true,
+	"key":           true,
+	"dsn":           true,
+}
+
+const redacted = "[REDACTED]"
+
+// redactor holds the HMAC key names are pseudonymized with
+type redactor struct {
+	key []byte
+}
+
+// newRedactor uses key, or a random key when it is empty; pseudonyms then
+// only correlate within one process
+func newRedactor(key []byte) redactor {
+	if len(key) == 0 {
+		key = make([]byte, sha256.Size)
+		_, _ = rand.Read(key)
+	}
+	return redactor{key: key}
+}
+
+// replaceAttr is the slog.HandlerOptions.ReplaceAttr of the JSON handler
+func (r redactor) replaceAttr(_ []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(attr.Key, redacted)
+	case piiKeys[key]:
+		return slog.String(attr.Key, r.pseudonym(attr.Value.String()))
+	}
+	if doc, ok := attr.Value.Any().(JSON); ok {
+		return slog.Any(attr.Key, r.redactJSON(doc))
+	}
+	return attr
+}
+
+func (r redactor) pseudonym(name string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(name))
+	return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// JSON is a document, such as an event payload, that is logged with the
+// same redaction applied to its keys
+type JSON []byte
+
+// RedactJSON marks data to be redacted by the logger it is logged with
+func RedactJSON(data []byte) JSON {
+	return JSON(data)
+}
+
+// redactJSON redacts doc; documents that cannot be parsed are redacted as
+// a whole
+func (r redactor) redactJSON(doc JSON) json.RawMessage {
+	var value any
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return json.RawMessage(`"` + redacted + `"`)
+	}
+	out, err := json.Marshal(r.redactValue(value))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+func (r redactor) redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			switch lower := strings.ToLower(key); {
+			case secretKeys[lower]:
+				v[key] = redacted
+			case piiKeys[lower]:
+				if name, ok := field.(string); ok {
+					v[key] = r.pseudonym(name)
+				}
+			default:
+				v[key] = r.redactValue(field)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactValue(item)
+		}
+	}
+	return value
+}
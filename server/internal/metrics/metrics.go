@@ -0,0 +1,110 @@
+// Package metrics defines the Prometheus metrics the server exports on
+// /metrics. Collectors are registered with the default registry, which
+// also carries the Go runtime and process metrics.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"simple_bank/server/internal/apperr"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "simple_bank"
+
+var (
+	// HTTPRequestDuration is labelled with the gin route rather than the
+	// path, so that account IDs and owner names do not become labels
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests by route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	Transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ledger",
+		Name:      "transfers_total",
+		Help:      "Completed transfers by currency.",
+	}, []string{"currency"})
+
+	TransferVolume = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ledger",
+		Name:      "transfer_volume_total",
+		Help:      "Amount moved by completed transfers in minor units, by currency.",
+	}, []string{"currency"})
+
+	// TransferFailures is labelled with the lower-cased error code, such
+	// as insufficient_funds
+	TransferFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ledger",
+		Name:      "transfer_failures_total",
+		Help:      "Rejected or failed transfers by reason.",
+	}, []string{"reason"})
+
+	LockWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "lock_wait_seconds",
+		Help:      "Time spent acquiring account row locks.",
+		Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5},
+	})
+
+	TransactionRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transaction_retries_total",
+		Help:      "Transactions run again after a serialization failure, by operation.",
+	}, []string{"operation"})
+
+	// WorkerLag is how far behind each background worker is: the age of
+	// the oldest item in its last batch, or zero when it is idle
+	WorkerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "worker",
+		Name:      "lag_seconds",
+		Help:      "Age of the oldest item in the last batch processed by each background worker.",
+	}, []string{"worker"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports the connection pool statistics of db
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveTransfer counts a finished CreateTransfer call. currency is empty
+// when the transfer failed before its accounts were loaded.
+func ObserveTransfer(currency string, amount int64, err error) {
+	if err != nil {
+		TransferFailures.WithLabelValues(strings.ToLower(string(apperr.CodeOf(err)))).Inc()
+		return
+	}
+	Transfers.WithLabelValues(currency).Inc()
+	TransferVolume.WithLabelValues(currency).Add(float64(amount))
+}
+
+// ObserveLag sets the lag of worker from the oldest item of its last batch.
+// A zero time means the batch was empty.
+func ObserveLag(worker string, oldest time.Time) {
+	if oldest.IsZero() {
+		WorkerLag.WithLabelValues(worker).Set(0)
+		return
+	}
+	WorkerLag.WithLabelValues(worker).Set(time.Since(oldest).Seconds())
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"simple_bank/server/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs every request once it has been served. Requests are
+// identified by their route rather than their path, which may contain
+// owner names.
+func AccessLog() gin.HandlerFunc {
+	log := logging.For("http")
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		log.Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration", time.Since(start),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
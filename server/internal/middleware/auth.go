@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate resolves the caller with the first authenticator that
+// recognises the request credentials and stores the principal in the
+// request context. Requests without valid credentials are rejected; when
+// the credentials could not be checked, such as while the database is
+// down, the request fails as a server error instead.
+func Authenticate(authenticators ...auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, authenticator := range authenticators {
+			principal, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, auth.ErrNoCredentials) {
+				continue
+			}
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				c.Header("WWW-Authenticate", "Bearer")
+				abort(c, apperr.New(apperr.CodeUnauthenticated, "invalid credentials"))
+				return
+			}
+			if err != nil {
+				abort(c, err)
+				return
+			}
+
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", "Bearer")
+		abort(c, auth.ErrUnauthenticated)
+	}
+}
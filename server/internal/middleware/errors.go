@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/requestinfo"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of error responses
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error response. Clients should branch on Code
+// rather than on Detail, which is meant for people. Instance is the route
+// template rather than the path, which may hold owners or other personal
+// data; the request ID identifies the occurrence.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      apperr.Code `json:"code"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// RenderErrors writes the last error handlers and middleware attached to
+// the context with c.Error as a problem response, unless a response was
+// already written. Errors without an apperr code are logged and reported
+// as internal errors without their details.
+func RenderErrors() gin.HandlerFunc {
+	log := logging.For("http")
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		appErr := apperr.From(err)
+		status := appErr.Code.HTTPStatus()
+		if status >= http.StatusInternalServerError {
+			log.ErrorContext(c.Request.Context(), "request failed",
+				"method", c.Request.Method, "route", c.FullPath(), "error", err)
+		}
+
+		c.Header("Content-Type", ProblemContentType)
+		c.JSON(status, Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    appErr.Message,
+			Instance:  c.FullPath(),
+			Code:      appErr.Code,
+			RequestID: requestinfo.FromContext(c.Request.Context()).RequestID,
+		})
+	}
+}
+
+// Recover turns panics into internal errors for RenderErrors, so that they
+// are logged and answered like any other failure
+func Recover() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		abort(c, fmt.Errorf("panic: %v\n%s", recovered, debug.Stack()))
+	})
+}
+
+// abort stops the handler chain and leaves err for RenderErrors
+func abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
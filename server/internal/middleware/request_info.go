@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"simple_bank/server/internal/requestinfo"
+	"simple_bank/server/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// RequestInfo assigns every request an ID, reusing a well-formed one sent
+// by the client, and stores it with the client IP in the request context
+func RequestInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestinfo.ValidID(requestID) {
+			requestID = requestinfo.NewID()
+		}
+
+		c.Header(RequestIDHeader, requestID)
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(tracing.RequestIDKey.String(requestID))
+		c.Request = c.Request.WithContext(requestinfo.WithInfo(c.Request.Context(), requestinfo.Info{
+			RequestID: requestID,
+			SourceIP:  c.ClientIP(),
+		}))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireStaff restricts a route group to bank staff. Individual
+// permissions are checked by the services.
+func RequireStaff() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			abort(c, auth.ErrUnauthenticated)
+			return
+		}
+		if principal.IsService() || !principal.Role.IsStaff() {
+			abort(c, apperr.New(apperr.CodeForbidden, "staff access required"))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -7,17 +7,46 @@ import (
 type Account struct {
 	ID        int64     `gorm:"primaryKey;autoIncrement;not null" json:"id"`
 	Owner     string    `gorm:"type:varchar;not null;index" json:"owner"`
-	Balance   int64     `gorm:"type:bigint;not null;default:0" json:"balance"`
+	Balance   int64     `gorm:"type:bigint;not null" json:"balance"`
 	Currency  string    `gorm:"type:varchar;not null" json:"currency"`
+	Type      string    `gorm:"type:varchar;not null;default:checking" json:"type"`
+	Status    string    `gorm:"type:varchar;not null;default:active" json:"status"`
 	CreatedAt time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
 	Entries   []Entry   `gorm:"foreignKey:AccountID" json:"entries,omitempty"`
+	// Multiple is set when the account's type allowed the owner several
+	// accounts of it per currency; other accounts are unique per owner,
+	// currency and type
+	Multiple bool `gorm:"not null;default:false" json:"-"`
 	// FromTransfers are transfers where this account is the sender
 	FromTransfers []Transfer `gorm:"foreignKey:FromAccountID" json:"from_transfers,omitempty"`
 	// ToTransfers are transfers where this account is the receiver
 	ToTransfers []Transfer `gorm:"foreignKey:ToAccountID" json:"to_transfers,omitempty"`
 }
 
+// Account statuses
+const (
+	AccountStatusActive = "active"
+	// AccountStatusFrozen accounts can neither send nor receive transfers
+	AccountStatusFrozen = "frozen"
+)
+
+// Account types
+const (
+	AccountTypeChecking = "checking"
+	AccountTypeSavings  = "savings"
+)
+
+// ValidAccountType reports whether accountType is a known account type
+func ValidAccountType(accountType string) bool {
+	return accountType == AccountTypeChecking || accountType == AccountTypeSavings
+}
+
 // TableName specifies the table name for GORM
 func (Account) TableName() string {
 	return "accounts"
 }
+
+// Frozen reports whether the account has been frozen by an operator
+func (a *Account) Frozen() bool {
+	return a.Status == AccountStatusFrozen
+}
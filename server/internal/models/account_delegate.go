@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// AccountDelegate grants a user other than the owner access to an account
+type AccountDelegate struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	AccountID   int64     `gorm:"type:bigint;not null;uniqueIndex:idx_account_delegate" json:"account_id"`
+	Delegate    string    `gorm:"type:varchar;not null;uniqueIndex:idx_account_delegate" json:"delegate"`
+	CanTransfer bool      `gorm:"not null;default:false" json:"can_transfer"` // View-only when false
+	CreatedAt   time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AccountDelegate) TableName() string {
+	return "account_delegates"
+}
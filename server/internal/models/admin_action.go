@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// AdminAction records an operation performed through the admin API
+type AdminAction struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Actor      string    `gorm:"type:varchar;not null;index" json:"actor"`
+	Role       string    `gorm:"type:varchar;not null" json:"role"`
+	Action     string    `gorm:"type:varchar;not null" json:"action"`
+	TargetType string    `gorm:"type:varchar;not null" json:"target_type"`
+	TargetID   int64     `gorm:"type:bigint;not null" json:"target_id"` // Zero for actions without a single target
+	Reason     string    `gorm:"type:varchar;not null;default:''" json:"reason"`
+	CreatedAt  time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AdminAction) TableName() string {
+	return "admin_actions"
+}
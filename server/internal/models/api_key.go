@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey authenticates a service-to-service client. Only a hash of the
+// key is stored; Prefix identifies the key without revealing it.
+type APIKey struct {
+	ID         int64          `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Name       string         `gorm:"type:varchar;not null" json:"name"`
+	Prefix     string         `gorm:"type:varchar;not null;uniqueIndex" json:"prefix"`
+	KeyHash    string         `gorm:"type:varchar;not null" json:"-"`
+	Scopes     pq.StringArray `gorm:"type:text[];not null" json:"scopes"`
+	AccountIDs pq.Int64Array  `gorm:"type:bigint[];not null" json:"account_ids"` // Empty means any account
+	CreatedBy  string         `gorm:"type:varchar;not null" json:"created_by"`
+	ExpiresAt  *time.Time     `gorm:"type:timestamptz" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time     `gorm:"type:timestamptz" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time      `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Active reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
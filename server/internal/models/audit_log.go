@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog is an append-only record of a mutation. The database rejects
+// updates and deletes on the table.
+type AuditLog struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Actor      string    `gorm:"type:varchar;not null;index" json:"actor"`
+	Action     string    `gorm:"type:varchar;not null" json:"action"`
+	TargetType string    `gorm:"type:varchar;not null" json:"target_type"`
+	TargetID   int64     `gorm:"type:bigint;not null" json:"target_id"`
+	Before     JSON      `gorm:"type:jsonb" json:"before"` // Null for creations
+	After      JSON      `gorm:"type:jsonb" json:"after"`  // Null for deletions
+	RequestID  string    `gorm:"type:varchar;not null;default:''" json:"request_id"`
+	SourceIP   string    `gorm:"type:varchar;not null;default:''" json:"source_ip"`
+	CreatedAt  time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -5,11 +5,15 @@ import (
 )
 
 type Entry struct {
-	ID        int64     `gorm:"primaryKey;autoIncrement;not null" json:"id"`
-	AccountID int64     `gorm:"type:bigint;not null;index" json:"account_id"`
-	Amount    int64     `gorm:"type:bigint;not null" json:"amount"` // Can be negative or positive
-	CreatedAt time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
-	Account   Account   `gorm:"foreignKey:AccountID" json:"account,omitempty"`
+	ID         int64     `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	AccountID  int64     `gorm:"type:bigint;not null;index" json:"account_id"`
+	Amount     int64     `gorm:"type:bigint;not null" json:"amount"`             // Can be negative or positive
+	TransferID *int64    `gorm:"type:bigint;index" json:"transfer_id,omitempty"` // Set for entries made by a transfer
+	CreatedAt  time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	Account    Account   `gorm:"foreignKey:AccountID" json:"account,omitempty"`
+	// RunningBalance is the account balance after this entry according to
+	// the ledger; it is computed on read and only set by listings that ask for it
+	RunningBalance *int64 `gorm:"->;-:migration" json:"running_balance,omitempty"`
 }
 
 // TableName specifies the table name for GORM
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes and delivered to sinks by the outbox relay
+type OutboxEvent struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	EventType     string     `gorm:"type:varchar;not null" json:"event_type"`
+	AggregateType string     `gorm:"type:varchar;not null" json:"aggregate_type"`
+	AggregateID   int64      `gorm:"type:bigint;not null" json:"aggregate_id"`
+	Payload       JSON       `gorm:"type:jsonb;not null" json:"payload"`
+	CreatedAt     time.Time  `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	PublishedAt   *time.Time `gorm:"type:timestamptz" json:"published_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -12,6 +12,8 @@ type Transfer struct {
 	ToAccountID   int64     `gorm:"type:bigint;not null;index" json:"to_account_id"`
 	Amount        int64     `gorm:"type:bigint;not null" json:"amount"` // Must be positive
 	CreatedAt     time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	// ReversalOf is the transfer this one sent back, set on reversals only
+	ReversalOf *int64 `gorm:"type:bigint" json:"reversal_of,omitempty"`
 	// Define composite index
 	FromAccount Account `gorm:"foreignKey:FromAccountID" json:"from_account,omitempty"`
 	ToAccount   Account `gorm:"foreignKey:ToAccountID" json:"to_account,omitempty"`
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookSubscription registers a URL for events on an account
+type WebhookSubscription struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	Owner     string `gorm:"type:varchar;not null;index" json:"owner"`
+	AccountID int64  `gorm:"type:bigint;not null;index" json:"account_id"`
+	URL       string `gorm:"type:varchar;not null" json:"url"`
+	// Secret signs deliveries. It has to be kept in the clear to compute
+	// signatures and is only returned when the subscription is created.
+	Secret     string         `gorm:"type:varchar;not null" json:"-"`
+	EventTypes pq.StringArray `gorm:"type:text[];not null" json:"event_types"`
+	// BalanceThreshold triggers balance.below_threshold when crossed
+	BalanceThreshold *int64    `gorm:"type:bigint" json:"balance_threshold,omitempty"`
+	CreatedAt        time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	// DeliveryStatusDead deliveries exhausted their retries
+	DeliveryStatusDead = "dead"
+)
+
+// WebhookDelivery is one event queued for one subscription
+type WebhookDelivery struct {
+	ID             int64               `gorm:"primaryKey;autoIncrement;not null" json:"id"`
+	SubscriptionID int64               `gorm:"type:bigint;not null;uniqueIndex:idx_delivery_event" json:"subscription_id"`
+	OutboxEventID  int64               `gorm:"type:bigint;not null;uniqueIndex:idx_delivery_event" json:"outbox_event_id"`
+	EventType      string              `gorm:"type:varchar;not null" json:"event_type"`
+	Payload        JSON                `gorm:"type:jsonb;not null" json:"payload"`
+	Status         string              `gorm:"type:varchar;not null;default:pending" json:"status"`
+	Attempts       int                 `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time           `gorm:"type:timestamptz;not null" json:"next_attempt_at"`
+	LastError      string              `gorm:"type:varchar;not null;default:''" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time          `gorm:"type:timestamptz" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time           `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID" json:"-"`
+}
+
+// TableName specifies the table name for GORM
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,80 @@
+// Package openapi holds the OpenAPI 3 description of the REST API and
+// serves it together with an embedded Swagger UI.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Load parses and validates the embedded specification
+func Load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("parse openapi spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return doc, nil
+}
+
+// MustLoad is Load for callers that cannot run without the specification
+func MustLoad() *openapi3.T {
+	doc, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// JSON renders the specification as JSON
+func JSON(doc *openapi3.T) ([]byte, error) {
+	return json.Marshal(doc)
+}
+
+// initializer replaces the Swagger UI default, which loads the petstore
+// example, with one that loads specURL
+const initializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: %q,
+    dom_id: '#swagger-ui',
+    deepLinking: true,
+    presets: [
+      SwaggerUIBundle.presets.apis,
+      SwaggerUIStandalonePreset
+    ],
+    plugins: [
+      SwaggerUIBundle.plugins.DownloadUrl
+    ],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// SwaggerUI serves the Swagger UI files, pointed at specURL. Mount it with
+// http.StripPrefix so that requested paths are relative to the UI root.
+func SwaggerUI(specURL string) http.Handler {
+	files := http.FileServer(http.FS(swaggerFiles.FS))
+	script := fmt.Sprintf(initializer, specURL)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimPrefix(r.URL.Path, "/") == "swagger-initializer.js" {
+			w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+			fmt.Fprint(w, script)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"simple_bank/server/internal/models"
 	"time"
 
@@ -9,84 +10,108 @@ import (
 )
 
 type AccountRepository interface {
-	Create(account *models.Account) error
-	GetByID(id int64) (*models.Account, error)
-	GetByOwner(owner string, limit, offset int) ([]models.Account, error)
-	List(page, pageSize int) ([]models.Account, error)
-	Update(account *models.Account) error
-	Delete(id int64) error
-	UpdateBalance(id int64, amount int64) error
-	GetForUpdate(id int64) (*models.Account, error)
+	Create(ctx context.Context, account *models.Account) error
+	GetByID(ctx context.Context, id int64) (*models.Account, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]models.Account, error)
+	GetByOwner(ctx context.Context, owner string, page Page) ([]models.Account, error)
+	List(ctx context.Context, page Page) ([]models.Account, error)
+	UpdateStatus(ctx context.Context, id int64, status string) error
+	Delete(ctx context.Context, id int64) error
+	UpdateBalance(ctx context.Context, id int64, amount int64) error
+	GetForUpdate(ctx context.Context, id int64) (*models.Account, error)
 }
 
 type accountRepository struct {
-	db *gorm.DB
+	session
 }
 
-func NewAccountRepository(db *gorm.DB) AccountRepository {
-	return &accountRepository{db: db}
+func NewAccountRepository(db *gorm.DB, timeouts Timeouts) AccountRepository {
+	return &accountRepository{session{db: db, timeouts: timeouts}}
 }
 
 // Create a new account
-func (r *accountRepository) Create(account *models.Account) error {
+func (r *accountRepository) Create(ctx context.Context, account *models.Account) error {
 	// Set created_at if not set
 	if account.CreatedAt.IsZero() {
 		account.CreatedAt = time.Now()
 	}
-	return r.db.Create(account).Error
+	db, cancel := r.write(ctx)
+	defer cancel()
+	return db.Create(account).Error
 }
 
 // Get account by ID
-func (r *accountRepository) GetByID(id int64) (*models.Account, error) {
+func (r *accountRepository) GetByID(ctx context.Context, id int64) (*models.Account, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var account models.Account
-	err := r.db.First(&account, id).Error
+	err := db.First(&account, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &account, nil
 }
 
-// Get accounts by owner with pagination
-func (r *accountRepository) GetByOwner(owner string, limit, offset int) ([]models.Account, error) {
+// Get accounts by IDs; missing IDs are skipped
+func (r *accountRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Account, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var accounts []models.Account
-	err := r.db.Where("owner = ?", owner).
-		Limit(limit).Offset(offset).
-		Order("created_at DESC").
+	err := db.Where("id IN ?", ids).Find(&accounts).Error
+	return accounts, err
+}
+
+// Get accounts by owner, newest first
+func (r *accountRepository) GetByOwner(ctx context.Context, owner string, page Page) ([]models.Account, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	var accounts []models.Account
+	err := page.scope(db.Where("owner = ?", owner)).
 		Find(&accounts).Error
 	return accounts, err
 }
 
-// List accounts with pagination
-func (r *accountRepository) List(page, pageSize int) ([]models.Account, error) {
+// List accounts, newest first
+func (r *accountRepository) List(ctx context.Context, page Page) ([]models.Account, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var accounts []models.Account
-	offset := (page - 1) * pageSize
-	err := r.db.Limit(pageSize).Offset(offset).
-		Order("created_at DESC").
+	err := page.scope(db).
 		Find(&accounts).Error
 	return accounts, err
 }
 
-// Update account
-func (r *accountRepository) Update(account *models.Account) error {
-	return r.db.Save(account).Error
+// Update account status, leaving the other columns as they are
+func (r *accountRepository) UpdateStatus(ctx context.Context, id int64, status string) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+	return db.Model(&models.Account{}).
+		Where("id = ?", id).
+		Update("status", status).Error
 }
 
 // Delete account
-func (r *accountRepository) Delete(id int64) error {
-	return r.db.Delete(&models.Account{}, id).Error
+func (r *accountRepository) Delete(ctx context.Context, id int64) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+	return db.Delete(&models.Account{}, id).Error
 }
 
 // Update account balance (atomic update)
-func (r *accountRepository) UpdateBalance(id int64, amount int64) error {
-	return r.db.Model(&models.Account{}).
+func (r *accountRepository) UpdateBalance(ctx context.Context, id int64, amount int64) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+	return db.Model(&models.Account{}).
 		Where("id = ?", id).
 		Update("balance", gorm.Expr("balance + ?", amount)).Error
 }
 
 // Get account for update (with row lock)
-func (r *accountRepository) GetForUpdate(id int64) (*models.Account, error) {
+func (r *accountRepository) GetForUpdate(ctx context.Context, id int64) (*models.Account, error) {
+	db, cancel := r.write(ctx)
+	defer cancel()
 	var account models.Account
-	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
 		First(&account, id).Error
 	if err != nil {
 		return nil, err
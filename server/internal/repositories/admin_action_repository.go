@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"simple_bank/server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminActionRepository interface {
+	Create(action *models.AdminAction) error
+	List(page, pageSize int) ([]models.AdminAction, error)
+}
+
+type adminActionRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminActionRepository(db *gorm.DB) AdminActionRepository {
+	return &adminActionRepository{db: db}
+}
+
+func (r *adminActionRepository) Create(action *models.AdminAction) error {
+	if action.CreatedAt.IsZero() {
+		action.CreatedAt = time.Now()
+	}
+	return r.db.Create(action).Error
+}
+
+func (r *adminActionRepository) List(page, pageSize int) ([]models.AdminAction, error) {
+	var actions []models.AdminAction
+	offset := (page - 1) * pageSize
+	err := r.db.Limit(pageSize).Offset(offset).
+		Order("created_at DESC").
+		Find(&actions).Error
+	return actions, err
+}
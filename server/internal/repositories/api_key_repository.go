@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"simple_bank/server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	GetByID(id int64) (*models.APIKey, error)
+	GetByPrefix(prefix string) (*models.APIKey, error)
+	List(page, pageSize int) ([]models.APIKey, error)
+	Revoke(id int64, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) GetByID(id int64) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.First(&key, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(page, pageSize int) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	offset := (page - 1) * pageSize
+	err := r.db.Limit(pageSize).Offset(offset).
+		Order("created_at DESC").
+		Find(&keys).Error
+	return keys, err
+}
+
+// Revoke marks a key as revoked, keeping the first revocation time
+func (r *apiKeyRepository) Revoke(id int64, at time.Time) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
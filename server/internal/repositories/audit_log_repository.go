@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"simple_bank/server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter narrows an audit log query; zero fields are ignored
+type AuditLogFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   int64
+	RequestID  string
+}
+
+// AuditLogRepository only appends and reads; audit logs are never changed
+type AuditLogRepository interface {
+	Create(log *models.AuditLog) error
+	List(filter AuditLogFilter, page, pageSize int) ([]models.AuditLog, error)
+}
+
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(log *models.AuditLog) error {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	return r.db.Create(log).Error
+}
+
+func (r *auditLogRepository) List(filter AuditLogFilter, page, pageSize int) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	query := r.db.Model(&models.AuditLog{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+
+	offset := (page - 1) * pageSize
+	err := query.Limit(pageSize).Offset(offset).
+		Order("created_at DESC, id DESC").
+		Find(&logs).Error
+	return logs, err
+}
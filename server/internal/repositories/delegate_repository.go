@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"simple_bank/server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DelegateRepository interface {
+	Upsert(delegate *models.AccountDelegate) error
+	Get(accountID int64, delegate string) (*models.AccountDelegate, error)
+	GetByAccountID(accountID int64) ([]models.AccountDelegate, error)
+	Delete(accountID int64, delegate string) error
+}
+
+type delegateRepository struct {
+	db *gorm.DB
+}
+
+func NewDelegateRepository(db *gorm.DB) DelegateRepository {
+	return &delegateRepository{db: db}
+}
+
+// Upsert grants a delegate access, updating the permission if already granted
+func (r *delegateRepository) Upsert(delegate *models.AccountDelegate) error {
+	if delegate.CreatedAt.IsZero() {
+		delegate.CreatedAt = time.Now()
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "account_id"}, {Name: "delegate"}},
+		DoUpdates: clause.AssignmentColumns([]string{"can_transfer"}),
+	}).Create(delegate).Error
+}
+
+func (r *delegateRepository) Get(accountID int64, delegate string) (*models.AccountDelegate, error) {
+	var grant models.AccountDelegate
+	err := r.db.Where("account_id = ? AND delegate = ?", accountID, delegate).
+		First(&grant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (r *delegateRepository) GetByAccountID(accountID int64) ([]models.AccountDelegate, error) {
+	var grants []models.AccountDelegate
+	err := r.db.Where("account_id = ?", accountID).
+		Order("created_at DESC").
+		Find(&grants).Error
+	return grants, err
+}
+
+func (r *delegateRepository) Delete(accountID int64, delegate string) error {
+	return r.db.Where("account_id = ? AND delegate = ?", accountID, delegate).
+		Delete(&models.AccountDelegate{}).Error
+}
@@ -1,52 +1,192 @@
 package repositories
 
 import (
+	"context"
 	"simple_bank/server/internal/models"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// EntryType tells entries made by transfers from the rest
+type EntryType string
+
+const (
+	EntryTypeTransfer EntryType = "transfer"
+	// EntryTypeAdjustment covers opening balances and staff adjustments
+	EntryTypeAdjustment EntryType = "adjustment"
+)
+
+// EntryFilter narrows an entry listing; zero fields are ignored
+type EntryFilter struct {
+	// CreatedFrom and CreatedTo bound created_at to [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// MinAmount and MaxAmount bound the size of the entry whatever its sign,
+	// which is also what entries are sorted by for SortAmount
+	MinAmount *int64
+	MaxAmount *int64
+	// Incoming entries credit the account, outgoing ones debit it
+	Direction Direction
+	// CounterpartyID keeps entries of transfers with that account
+	CounterpartyID int64
+	Currency       string
+	Type           EntryType
+}
+
+func (f EntryFilter) apply(query *gorm.DB) *gorm.DB {
+	if !f.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", f.CreatedTo)
+	}
+	if f.MinAmount != nil {
+		query = query.Where("(amount >= ? OR amount <= ?)", *f.MinAmount, -*f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		query = query.Where("amount BETWEEN ? AND ?", -*f.MaxAmount, *f.MaxAmount)
+	}
+	switch f.Direction {
+	case DirectionIncoming:
+		query = query.Where("amount > 0")
+	case DirectionOutgoing:
+		query = query.Where("amount < 0")
+	}
+	if f.CounterpartyID != 0 {
+		query = query.Where(`transfer_id IN (
+			SELECT id FROM transfers WHERE from_account_id = @id OR to_account_id = @id
+		)`, map[string]any{"id": f.CounterpartyID})
+	}
+	if f.Currency != "" {
+		query = query.Where("account_id IN (SELECT id FROM accounts WHERE currency = ?)", f.Currency)
+	}
+	switch f.Type {
+	case EntryTypeTransfer:
+		query = query.Where("transfer_id IS NOT NULL")
+	case EntryTypeAdjustment:
+		query = query.Where("transfer_id IS NULL")
+	}
+	return query
+}
+
+// withRunningBalance selects entries together with the account balance
+// after each one, summed from the ledger
+const withRunningBalance = `entries.*, (
+	SELECT sum(prior.amount) FROM entries prior
+	WHERE prior.account_id = entries.account_id
+		AND (prior.created_at, prior.id) <= (entries.created_at, entries.id)
+) AS running_balance`
+
 type EntryRepository interface {
-	Create(entry *models.Entry) error
-	GetByID(id int64) (*models.Entry, error)
-	GetByAccountID(accountID int64, limit, offset int) ([]models.Entry, error)
-	List(page, pageSize int) ([]models.Entry, error)
+	Create(ctx context.Context, entry *models.Entry) error
+	GetByID(ctx context.Context, id int64) (*models.Entry, error)
+	// GetWithBalanceByID and ListWithBalanceByAccountID also compute the
+	// running balance of each entry
+	GetWithBalanceByID(ctx context.Context, id int64) (*models.Entry, error)
+	ListWithBalanceByAccountID(ctx context.Context, accountID int64, filter EntryFilter, page Page) ([]models.Entry, error)
+	GetByAccountID(ctx context.Context, accountID int64, filter EntryFilter, page Page) ([]models.Entry, error)
+	CountByAccountID(ctx context.Context, accountID int64, filter EntryFilter) (int64, error)
+	ListByAccountIDs(ctx context.Context, accountIDs []int64, page Page) (map[int64][]models.Entry, error)
+	List(ctx context.Context, page Page) ([]models.Entry, error)
 }
 
 type entryRepository struct {
-	db *gorm.DB
+	session
 }
 
-func NewEntryRepository(db *gorm.DB) EntryRepository {
-	return &entryRepository{db: db}
+func NewEntryRepository(db *gorm.DB, timeouts Timeouts) EntryRepository {
+	return &entryRepository{session{db: db, timeouts: timeouts}}
 }
 
-func (r *entryRepository) Create(entry *models.Entry) error {
-	return r.db.Create(entry).Error
+func (r *entryRepository) Create(ctx context.Context, entry *models.Entry) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+	return db.Create(entry).Error
 }
 
-func (r *entryRepository) GetByID(id int64) (*models.Entry, error) {
+func (r *entryRepository) GetByID(ctx context.Context, id int64) (*models.Entry, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var entry models.Entry
-	err := r.db.Preload("Account").First(&entry, id).Error
+	err := db.Preload("Account").First(&entry, id).Error
 	return &entry, err
 }
 
-func (r *entryRepository) GetByAccountID(accountID int64, limit, offset int) ([]models.Entry, error) {
+func (r *entryRepository) GetWithBalanceByID(ctx context.Context, id int64) (*models.Entry, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	var entry models.Entry
+	err := db.Select(withRunningBalance).Preload("Account").First(&entry, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *entryRepository) ListWithBalanceByAccountID(ctx context.Context, accountID int64, filter EntryFilter, page Page) ([]models.Entry, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	var entries []models.Entry
+	err := page.sizes().scope(filter.apply(db.Select(withRunningBalance).Preload("Account"))).
+		Where("account_id = ?", accountID).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *entryRepository) GetByAccountID(ctx context.Context, accountID int64, filter EntryFilter, page Page) ([]models.Entry, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var entries []models.Entry
-	err := r.db.Preload("Account").
+	err := page.sizes().scope(filter.apply(db.Preload("Account"))).
 		Where("account_id = ?", accountID).
-		Limit(limit).Offset(offset).
-		Order("created_at DESC").
 		Find(&entries).Error
 	return entries, err
 }
 
-func (r *entryRepository) List(page, pageSize int) ([]models.Entry, error) {
+func (r *entryRepository) CountByAccountID(ctx context.Context, accountID int64, filter EntryFilter) (int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	var count int64
+	err := filter.apply(db.Model(&models.Entry{})).
+		Where("account_id = ?", accountID).
+		Count(&count).Error
+	return count, err
+}
+
+// ListByAccountIDs applies page to the entries of each account, in one
+// query. Pages continue from page.After; Offset is ignored.
+func (r *entryRepository) ListByAccountIDs(ctx context.Context, accountIDs []int64, page Page) (map[int64][]models.Entry, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	page = page.sizes()
+	after, args := page.after("e")
+	var entries []models.Entry
+	err := db.Raw(`
+		SELECT * FROM (
+			SELECT e.*, row_number() OVER (PARTITION BY e.account_id ORDER BY `+page.orderBy("e")+`) AS row_num
+			FROM entries e
+			WHERE e.account_id IN ? AND `+after+`
+		) ranked
+		WHERE row_num <= ?
+		ORDER BY account_id, row_num`, append(append([]any{accountIDs}, args...), page.Limit)...).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byAccount := make(map[int64][]models.Entry, len(accountIDs))
+	for _, entry := range entries {
+		byAccount[entry.AccountID] = append(byAccount[entry.AccountID], entry)
+	}
+	return byAccount, nil
+}
+
+func (r *entryRepository) List(ctx context.Context, page Page) ([]models.Entry, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var entries []models.Entry
-	offset := (page - 1) * pageSize
-	err := r.db.Preload("Account").
-		Limit(pageSize).Offset(offset).
-		Order("created_at DESC").
+	err := page.sizes().scope(db.Preload("Account")).
 		Find(&entries).Error
 	return entries, err
 }
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// BalanceMismatch is an account whose balance differs from the sum of its
+// ledger entries
+type BalanceMismatch struct {
+	AccountID int64 `json:"account_id"`
+	Balance   int64 `json:"balance"`
+	// Ledger is the sum of the account's entries
+	Ledger int64 `json:"ledger"`
+}
+
+// UnbalancedTransfer is a transfer whose entries do not move exactly its
+// amount from one account to the other
+type UnbalancedTransfer struct {
+	TransferID int64 `json:"transfer_id"`
+	Entries    int64 `json:"entries"`
+	// Net is the sum of the transfer's entries, zero when balanced
+	Net int64 `json:"net"`
+}
+
+// LedgerRepository checks balances against the entries recording them
+type LedgerRepository interface {
+	BalanceMismatches(ctx context.Context) ([]BalanceMismatch, error)
+	// UnbalancedTransfers skips transfers with no entries linked to them,
+	// which predate entries recording their transfer
+	UnbalancedTransfers(ctx context.Context) ([]UnbalancedTransfer, error)
+}
+
+type ledgerRepository struct {
+	session
+}
+
+func NewLedgerRepository(db *gorm.DB, timeouts Timeouts) LedgerRepository {
+	return &ledgerRepository{session{db: db, timeouts: timeouts}}
+}
+
+func (r *ledgerRepository) BalanceMismatches(ctx context.Context) ([]BalanceMismatch, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	var mismatches []BalanceMismatch
+	err := db.Raw(`
+		SELECT a.id AS account_id, a.balance, COALESCE(e.total, 0) AS ledger
+		FROM accounts a
+		LEFT JOIN (
+			SELECT account_id, SUM(amount) AS total FROM entries GROUP BY account_id
+		) e ON e.account_id = a.id
+		WHERE a.balance <> COALESCE(e.total, 0)
+		ORDER BY a.id`).
+		Scan(&mismatches).Error
+	return mismatches, err
+}
+
+func (r *ledgerRepository) UnbalancedTransfers(ctx context.Context) ([]UnbalancedTransfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	var unbalanced []UnbalancedTransfer
+	err := db.Raw(`
+		SELECT t.id AS transfer_id, COUNT(*) AS entries, SUM(e.amount) AS net
+		FROM transfers t
+		JOIN entries e ON e.transfer_id = t.id
+		GROUP BY t.id, t.amount
+		HAVING COUNT(*) <> 2 OR SUM(e.amount) <> 0 OR MAX(e.amount) <> t.amount
+		ORDER BY t.id`).
+		Scan(&unbalanced).Error
+	return unbalanced, err
+}
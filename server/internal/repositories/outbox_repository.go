@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"simple_bank/server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// relayLockKey is the advisory lock that keeps replicas from relaying
+// concurrently, which would publish events twice
+const relayLockKey = 7_231_001
+
+type OutboxRepository interface {
+	Create(event *models.OutboxEvent) error
+	// TryLockRelay takes the lock held by the replica relaying events,
+	// reporting false when another transaction holds it. Call it inside a
+	// transaction; the lock is held until it ends.
+	TryLockRelay() (bool, error)
+	// LockPending locks the oldest unpublished events and returns them
+	// grouped by aggregate, each aggregate's events in ID order. Call it
+	// inside a transaction; the locks are held until it ends.
+	LockPending(limit int) ([]models.OutboxEvent, error)
+	MarkPublished(ids []int64, at time.Time) error
+	GetByID(id int64) (*models.OutboxEvent, error)
+	// GetAfter returns events after afterID in ID order
+	GetAfter(afterID int64, limit int) ([]models.OutboxEvent, error)
+	// LastID returns the highest event ID, or zero when there are none
+	LastID() (int64, error)
+	// FirstIDSince returns the lowest ID of the events created at or after
+	// since, or zero when there are none
+	FirstIDSince(since time.Time) (int64, error)
+	// GetByAggregate returns an aggregate's events after afterID in ID order
+	GetByAggregate(aggregateType string, aggregateID, afterID int64, limit int) ([]models.OutboxEvent, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Create(event *models.OutboxEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	return r.db.Create(event).Error
+}
+
+func (r *outboxRepository) TryLockRelay() (bool, error) {
+	var locked bool
+	err := r.db.Raw("SELECT pg_try_advisory_xact_lock(?)", relayLockKey).Scan(&locked).Error
+	return locked, err
+}
+
+func (r *outboxRepository) LockPending(limit int) ([]models.OutboxEvent, error) {
+	oldest := r.db.Model(&models.OutboxEvent{}).
+		Select("id").
+		Where("published_at IS NULL").
+		Order("id ASC").
+		Limit(limit)
+
+	var events []models.OutboxEvent
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN (?)", oldest).
+		Order("aggregate_type, aggregate_id, id").
+		Find(&events).Error
+	return events, err
+}
+
+func (r *outboxRepository) MarkPublished(ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("published_at", at).Error
+}
+
+func (r *outboxRepository) GetByAggregate(aggregateType string, aggregateID, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("aggregate_type = ? AND aggregate_id = ? AND id > ?", aggregateType, aggregateID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *outboxRepository) GetByID(id int64) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	err := r.db.First(&event, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *outboxRepository) GetAfter(afterID int64, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.db.Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *outboxRepository) LastID() (int64, error) {
+	var id int64
+	err := r.db.Model(&models.OutboxEvent{}).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+func (r *outboxRepository) FirstIDSince(since time.Time) (int64, error) {
+	var id int64
+	err := r.db.Model(&models.OutboxEvent{}).
+		Select("COALESCE(MIN(id), 0)").
+		Where("created_at >= ?", since).
+		Scan(&id).Error
+	return id, err
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SortField is a column listings can be ordered by. Ties are broken by id
+// so that every row has a distinct position.
+type SortField string
+
+const (
+	SortCreatedAt SortField = "created_at"
+	// SortAmount applies to entries and transfers only
+	SortAmount SortField = "amount"
+)
+
+// Valid reports whether the field is one listings can be ordered by
+func (f SortField) Valid() bool {
+	return f == SortCreatedAt || f == SortAmount
+}
+
+// Cursor is the position of a row in a listing. Only the value of the
+// field the listing is sorted by is used.
+type Cursor struct {
+	CreatedAt time.Time
+	Amount    int64
+	ID        int64
+}
+
+// value returns the cursor's value for field
+func (c Cursor) value(field SortField) any {
+	if field == SortAmount {
+		return c.Amount
+	}
+	return c.CreatedAt
+}
+
+// Page selects part of a listing, newest first unless Sort or Ascending
+// say otherwise. When After is set the page starts after that row and
+// Offset is ignored, so rows inserted meanwhile are neither skipped nor
+// repeated.
+type Page struct {
+	Limit     int
+	Offset    int
+	After     *Cursor
+	Sort      SortField
+	Ascending bool
+	// bySize sorts amounts by their size whatever their sign
+	bySize bool
+}
+
+// field returns the sort field, falling back to created_at for unknown
+// ones since it is written into the query as is
+func (p Page) field() SortField {
+	if !p.Sort.Valid() {
+		return SortCreatedAt
+	}
+	return p.Sort
+}
+
+// column is the SQL expression sorted by, qualified with table when set
+func (p Page) column(table string) string {
+	column := string(p.field())
+	if table != "" {
+		column = table + "." + column
+	}
+	if p.bySize && p.field() == SortAmount {
+		column = "abs(" + column + ")"
+	}
+	return column
+}
+
+// sizes sorts the page's amounts by their size, for listings of signed
+// amounts such as ledger entries
+func (p Page) sizes() Page {
+	p.bySize = true
+	return p
+}
+
+// direction returns the SQL sort direction of the page and the comparison
+// that selects rows after a cursor
+func (p Page) direction() (string, string) {
+	if p.Ascending {
+		return " ASC", " > "
+	}
+	return " DESC", " < "
+}
+
+// scope orders query and applies the page to it
+func (p Page) scope(query *gorm.DB) *gorm.DB {
+	column := p.column("")
+	direction, compare := p.direction()
+
+	query = query.Order(column + direction).Order("id" + direction).Limit(p.Limit)
+	if p.After != nil {
+		return query.Where("("+column+", id)"+compare+"(?, ?)", p.After.value(p.field()), p.After.ID)
+	}
+	return query.Offset(p.Offset)
+}
+
+// prefix is the page covering every row up to the end of p, for subqueries
+// whose results are paged again by an outer query
+func (p Page) prefix() Page {
+	if p.After != nil {
+		return p
+	}
+	return Page{Limit: p.Offset + p.Limit, Sort: p.Sort, Ascending: p.Ascending}
+}
+
+// orderBy is the page's ORDER BY list for raw queries on table
+func (p Page) orderBy(table string) string {
+	direction, _ := p.direction()
+	return p.column(table) + direction + ", " + table + ".id" + direction
+}
+
+// after is the condition selecting the rows of table after p.After for raw
+// queries, or TRUE when the page starts at the beginning
+func (p Page) after(table string) (string, []any) {
+	if p.After == nil {
+		return "TRUE", nil
+	}
+	_, compare := p.direction()
+	return "(" + p.column(table) + ", " + table + ".id)" + compare + "(?, ?)",
+		[]any{p.After.value(p.field()), p.After.ID}
+}
@@ -1,17 +1,75 @@
 package repositories
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Repository struct {
-	Account  AccountRepository
-	Entry    EntryRepository
-	Transfer TransferRepository
+	Account     AccountRepository
+	Entry       EntryRepository
+	Transfer    TransferRepository
+	Ledger      LedgerRepository
+	Delegate    DelegateRepository
+	APIKey      APIKeyRepository
+	AdminAction AdminActionRepository
+	AuditLog    AuditLogRepository
+	Outbox      OutboxRepository
+	Webhook     WebhookSubscriptionRepository
+	Delivery    WebhookDeliveryRepository
 }
 
-func NewRepository(db *gorm.DB) *Repository {
+func NewRepository(db *gorm.DB, timeouts Timeouts) *Repository {
 	return &Repository{
-		Account:  NewAccountRepository(db),
-		Entry:    NewEntryRepository(db),
-		Transfer: NewTransferRepository(db),
+		Account:     NewAccountRepository(db, timeouts),
+		Entry:       NewEntryRepository(db, timeouts),
+		Transfer:    NewTransferRepository(db, timeouts),
+		Ledger:      NewLedgerRepository(db, timeouts),
+		Delegate:    NewDelegateRepository(db),
+		APIKey:      NewAPIKeyRepository(db),
+		AdminAction: NewAdminActionRepository(db),
+		AuditLog:    NewAuditLogRepository(db),
+		Outbox:      NewOutboxRepository(db),
+		Webhook:     NewWebhookSubscriptionRepository(db),
+		Delivery:    NewWebhookDeliveryRepository(db),
+	}
+}
+
+// Timeouts bound how long database work may take on top of the caller's
+// deadline. Zero means no limit of its own.
+type Timeouts struct {
+	// Read bounds each query
+	Read time.Duration
+	// Write bounds each insert, update or delete
+	Write time.Duration
+	// Transaction bounds a whole transaction, such as a transfer
+	Transaction time.Duration
+}
+
+func bound(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
 	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// session runs the queries of a repository under the caller's context
+type session struct {
+	db       *gorm.DB
+	timeouts Timeouts
+}
+
+// read returns the database for a query under ctx. The returned function
+// must be called once the query's results have been read.
+func (s session) read(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := bound(ctx, s.timeouts.Read)
+	return s.db.WithContext(ctx), cancel
+}
+
+// write is read for inserts, updates and deletes
+func (s session) write(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := bound(ctx, s.timeouts.Write)
+	return s.db.WithContext(ctx), cancel
 }
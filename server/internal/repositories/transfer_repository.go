@@ -1,75 +1,227 @@
 package repositories
 
 import (
+	"context"
 	"simple_bank/server/internal/models"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
+// Direction selects money moving into or out of an account
+type Direction string
+
+const (
+	DirectionIncoming Direction = "incoming"
+	DirectionOutgoing Direction = "outgoing"
+)
+
+// TransferFilter narrows a transfer listing; zero fields are ignored
+type TransferFilter struct {
+	// CreatedFrom and CreatedTo bound created_at to [CreatedFrom, CreatedTo)
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	MinAmount   *int64
+	MaxAmount   *int64
+	// Direction and CounterpartyID are relative to the listed account
+	Direction      Direction
+	CounterpartyID int64
+	Currency       string
+}
+
+// apply adds the conditions that do not depend on direction
+func (f TransferFilter) apply(query *gorm.DB) *gorm.DB {
+	if !f.CreatedFrom.IsZero() {
+		query = query.Where("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		query = query.Where("created_at < ?", f.CreatedTo)
+	}
+	if f.MinAmount != nil {
+		query = query.Where("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		query = query.Where("amount <= ?", *f.MaxAmount)
+	}
+	if f.Currency != "" {
+		// Transfers only move money between accounts of the same currency
+		query = query.Where("from_account_id IN (SELECT id FROM accounts WHERE currency = ?)", f.Currency)
+	}
+	return query
+}
+
 type TransferRepository interface {
-	Create(transfer *models.Transfer) error
-	GetByID(id int64) (*models.Transfer, error)
-	GetByAccountID(accountID int64, limit, offset int) ([]models.Transfer, error)
-	GetByFromAccountID(fromAccountID int64, limit, offset int) ([]models.Transfer, error)
-	GetByToAccountID(toAccountID int64, limit, offset int) ([]models.Transfer, error)
-	List(page, pageSize int) ([]models.Transfer, error)
+	Create(ctx context.Context, transfer *models.Transfer) error
+	GetByID(ctx context.Context, id int64) (*models.Transfer, error)
+	GetByIDs(ctx context.Context, ids []int64) ([]models.Transfer, error)
+	GetByAccountID(ctx context.Context, accountID int64, filter TransferFilter, page Page) ([]models.Transfer, error)
+	CountByAccountID(ctx context.Context, accountID int64, filter TransferFilter) (int64, error)
+	ListByAccountIDs(ctx context.Context, accountIDs []int64, page Page) (map[int64][]models.Transfer, error)
+	GetByFromAccountID(ctx context.Context, fromAccountID int64, page Page) ([]models.Transfer, error)
+	GetByToAccountID(ctx context.Context, toAccountID int64, page Page) ([]models.Transfer, error)
+	List(ctx context.Context, page Page) ([]models.Transfer, error)
 }
 
 type transferRepository struct {
-	db *gorm.DB
+	session
 }
 
-func NewTransferRepository(db *gorm.DB) TransferRepository {
-	return &transferRepository{db: db}
+func NewTransferRepository(db *gorm.DB, timeouts Timeouts) TransferRepository {
+	return &transferRepository{session{db: db, timeouts: timeouts}}
 }
 
-func (r *transferRepository) Create(transfer *models.Transfer) error {
-	return r.db.Create(transfer).Error
+func (r *transferRepository) Create(ctx context.Context, transfer *models.Transfer) error {
+	db, cancel := r.write(ctx)
+	defer cancel()
+	return db.Create(transfer).Error
 }
 
-func (r *transferRepository) GetByID(id int64) (*models.Transfer, error) {
+func (r *transferRepository) GetByID(ctx context.Context, id int64) (*models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var transfer models.Transfer
-	err := r.db.Preload("FromAccount").Preload("ToAccount").
+	err := db.Preload("FromAccount").Preload("ToAccount").
 		First(&transfer, id).Error
 	return &transfer, err
 }
 
-func (r *transferRepository) GetByAccountID(accountID int64, limit, offset int) ([]models.Transfer, error) {
+func (r *transferRepository) GetByIDs(ctx context.Context, ids []int64) ([]models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var transfers []models.Transfer
-	err := r.db.Preload("FromAccount").Preload("ToAccount").
-		Where("from_account_id = ? OR to_account_id = ?", accountID, accountID).
-		Limit(limit).Offset(offset).
-		Order("created_at DESC").
+	err := db.Preload("FromAccount").Preload("ToAccount").
+		Where("id IN ?", ids).
 		Find(&transfers).Error
 	return transfers, err
 }
 
-func (r *transferRepository) GetByFromAccountID(fromAccountID int64, limit, offset int) ([]models.Transfer, error) {
+// GetByAccountID pages through transfers sent or received by an account.
+// Each direction is paged separately so that both can use their account
+// index before the results are merged.
+func (r *transferRepository) GetByAccountID(ctx context.Context, accountID int64, filter TransferFilter, page Page) ([]models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	directions := r.directions(accountID, filter)
+	for i := range directions {
+		directions[i] = page.prefix().scope(directions[i].Select("id"))
+	}
+
+	condition, args := inDirections(directions)
+	var transfers []models.Transfer
+	err := page.scope(db.Preload("FromAccount").Preload("ToAccount")).
+		Where(condition, args...).
+		Find(&transfers).Error
+	return transfers, err
+}
+
+func (r *transferRepository) CountByAccountID(ctx context.Context, accountID int64, filter TransferFilter) (int64, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	directions := r.directions(accountID, filter)
+	for i := range directions {
+		directions[i] = directions[i].Select("id")
+	}
+
+	condition, args := inDirections(directions)
+	var count int64
+	err := db.Model(&models.Transfer{}).
+		Where(condition, args...).
+		Count(&count).Error
+	return count, err
+}
+
+// directions returns a query per direction the filter allows, each
+// selecting the account's transfers in that direction that match it
+func (r *transferRepository) directions(accountID int64, filter TransferFilter) []*gorm.DB {
+	var directions []*gorm.DB
+	direction := func(own, counterparty string) *gorm.DB {
+		query := r.db.Model(&models.Transfer{}).Where(own+" = ?", accountID)
+		if filter.CounterpartyID != 0 {
+			query = query.Where(counterparty+" = ?", filter.CounterpartyID)
+		}
+		return filter.apply(query)
+	}
+	if filter.Direction != DirectionIncoming {
+		directions = append(directions, direction("from_account_id", "to_account_id"))
+	}
+	if filter.Direction != DirectionOutgoing {
+		directions = append(directions, direction("to_account_id", "from_account_id"))
+	}
+	return directions
+}
+
+// inDirections is a condition matching the IDs selected by any of the
+// direction queries
+func inDirections(directions []*gorm.DB) (string, []any) {
+	conditions := make([]string, len(directions))
+	args := make([]any, len(directions))
+	for i, direction := range directions {
+		conditions[i] = "id IN (?)"
+		args[i] = direction
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// ListByAccountIDs applies page to the transfers sent or received by each
+// account, in one query. Pages continue from page.After; Offset is ignored.
+func (r *transferRepository) ListByAccountIDs(ctx context.Context, accountIDs []int64, page Page) (map[int64][]models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
+	after, args := page.after("t")
+	var rows []struct {
+		models.Transfer `gorm:"embedded"`
+		AccountID       int64
+	}
+	err := db.Raw(`
+		SELECT * FROM (
+			SELECT t.*, a.account_id,
+				row_number() OVER (PARTITION BY a.account_id ORDER BY `+page.orderBy("t")+`) AS row_num
+			FROM transfers t
+			JOIN unnest(?::bigint[]) AS a(account_id)
+				ON a.account_id IN (t.from_account_id, t.to_account_id)
+			WHERE `+after+`
+		) ranked
+		WHERE row_num <= ?
+		ORDER BY account_id, row_num`, append(append([]any{pq.Array(accountIDs)}, args...), page.Limit)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byAccount := make(map[int64][]models.Transfer, len(accountIDs))
+	for _, row := range rows {
+		byAccount[row.AccountID] = append(byAccount[row.AccountID], row.Transfer)
+	}
+	return byAccount, nil
+}
+
+func (r *transferRepository) GetByFromAccountID(ctx context.Context, fromAccountID int64, page Page) ([]models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var transfers []models.Transfer
-	err := r.db.Preload("FromAccount").Preload("ToAccount").
+	err := page.scope(db.Preload("FromAccount").Preload("ToAccount")).
 		Where("from_account_id = ?", fromAccountID).
-		Limit(limit).Offset(offset).
-		Order("created_at DESC").
 		Find(&transfers).Error
 	return transfers, err
 }
 
-func (r *transferRepository) GetByToAccountID(toAccountID int64, limit, offset int) ([]models.Transfer, error) {
+func (r *transferRepository) GetByToAccountID(ctx context.Context, toAccountID int64, page Page) ([]models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var transfers []models.Transfer
-	err := r.db.Preload("FromAccount").Preload("ToAccount").
+	err := page.scope(db.Preload("FromAccount").Preload("ToAccount")).
 		Where("to_account_id = ?", toAccountID).
-		Limit(limit).Offset(offset).
-		Order("created_at DESC").
 		Find(&transfers).Error
 	return transfers, err
 }
 
-func (r *transferRepository) List(page, pageSize int) ([]models.Transfer, error) {
+func (r *transferRepository) List(ctx context.Context, page Page) ([]models.Transfer, error) {
+	db, cancel := r.read(ctx)
+	defer cancel()
 	var transfers []models.Transfer
-	offset := (page - 1) * pageSize
-	err := r.db.Preload("FromAccount").Preload("ToAccount").
-		Limit(pageSize).Offset(offset).
-		Order("created_at DESC").
+	err := page.scope(db.Preload("FromAccount").Preload("ToAccount")).
 		Find(&transfers).Error
 	return transfers, err
 }
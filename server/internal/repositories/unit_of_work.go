@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs multi-step writes atomically. The repositories handed to
+// fn are bound to one transaction, which commits when fn returns nil and
+// rolls back otherwise.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(tx *Repository) error) error
+}
+
+type unitOfWork struct {
+	db       *gorm.DB
+	timeouts Timeouts
+}
+
+func NewUnitOfWork(db *gorm.DB, timeouts Timeouts) UnitOfWork {
+	return &unitOfWork{db: db, timeouts: timeouts}
+}
+
+// Do runs fn in a transaction bounded by the transaction timeout
+func (u *unitOfWork) Do(ctx context.Context, fn func(tx *Repository) error) error {
+	ctx, cancel := bound(ctx, u.timeouts.Transaction)
+	defer cancel()
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepository(tx, u.timeouts))
+	})
+}
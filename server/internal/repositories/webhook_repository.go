@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"simple_bank/server/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookSubscriptionRepository interface {
+	Create(subscription *models.WebhookSubscription) error
+	GetByID(id int64) (*models.WebhookSubscription, error)
+	GetByOwner(owner string) ([]models.WebhookSubscription, error)
+	// GetByAccountAndEvent returns the subscriptions of an account that
+	// listen for eventType
+	GetByAccountAndEvent(accountID int64, eventType string) ([]models.WebhookSubscription, error)
+	Delete(id int64) error
+}
+
+type webhookSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookSubscriptionRepository(db *gorm.DB) WebhookSubscriptionRepository {
+	return &webhookSubscriptionRepository{db: db}
+}
+
+func (r *webhookSubscriptionRepository) Create(subscription *models.WebhookSubscription) error {
+	if subscription.CreatedAt.IsZero() {
+		subscription.CreatedAt = time.Now()
+	}
+	return r.db.Create(subscription).Error
+}
+
+func (r *webhookSubscriptionRepository) GetByID(id int64) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	err := r.db.First(&subscription, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *webhookSubscriptionRepository) GetByOwner(owner string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Where("owner = ?", owner).
+		Order("created_at DESC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookSubscriptionRepository) GetByAccountAndEvent(accountID int64, eventType string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	err := r.db.Where("account_id = ? AND ? = ANY(event_types)", accountID, eventType).
+		Order("id ASC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookSubscriptionRepository) Delete(id int64) error {
+	return r.db.Delete(&models.WebhookSubscription{}, id).Error
+}
+
+type WebhookDeliveryRepository interface {
+	// Enqueue queues a delivery, ignoring one already queued for the same
+	// subscription and outbox event so relaying stays idempotent
+	Enqueue(delivery *models.WebhookDelivery) error
+	GetByID(id int64) (*models.WebhookDelivery, error)
+	List(status string, page, pageSize int) ([]models.WebhookDelivery, error)
+	// LockDue locks pending deliveries whose next attempt is due, skipping
+	// rows other dispatchers hold. Call it inside a transaction.
+	LockDue(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// Lease pushes the next attempt of deliveries to until, so that other
+	// dispatchers skip them while they are being sent
+	Lease(ids []int64, until time.Time) error
+	// Record saves the outcome of an attempt made under the lease ending
+	// at leasedUntil. It reports false, saving nothing, when the delivery
+	// was replayed or leased again in the meantime.
+	Record(delivery *models.WebhookDelivery, leasedUntil time.Time) (bool, error)
+	Update(delivery *models.WebhookDelivery) error
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Enqueue(delivery *models.WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Omit("Subscription").
+		Create(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) GetByID(id int64) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.First(&delivery, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) List(status string, page, pageSize int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := r.db.Model(&models.WebhookDelivery{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	offset := (page - 1) * pageSize
+	err := query.Limit(pageSize).Offset(offset).
+		Order("created_at DESC").
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookDeliveryRepository) LockDue(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, now).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil || len(deliveries) == 0 {
+		return deliveries, err
+	}
+
+	// Load subscriptions separately; FOR UPDATE cannot be combined with the
+	// joins a preload may need
+	ids := make([]int64, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.SubscriptionID
+	}
+	var subscriptions []models.WebhookSubscription
+	if err := r.db.Where("id IN ?", ids).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]models.WebhookSubscription, len(subscriptions))
+	for _, subscription := range subscriptions {
+		byID[subscription.ID] = subscription
+	}
+	for i := range deliveries {
+		deliveries[i].Subscription = byID[deliveries[i].SubscriptionID]
+	}
+	return deliveries, nil
+}
+
+func (r *webhookDeliveryRepository) Lease(ids []int64, until time.Time) error {
+	return r.db.Model(&models.WebhookDelivery{}).
+		Where("id IN ?", ids).
+		Update("next_attempt_at", until).Error
+}
+
+func (r *webhookDeliveryRepository) Record(delivery *models.WebhookDelivery, leasedUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, models.DeliveryStatusPending, leasedUntil).
+		Updates(map[string]any{
+			"status":          delivery.Status,
+			"attempts":        delivery.Attempts,
+			"next_attempt_at": delivery.NextAttemptAt,
+			"last_error":      delivery.LastError,
+			"delivered_at":    delivery.DeliveredAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *webhookDeliveryRepository) Update(delivery *models.WebhookDelivery) error {
+	return r.db.Omit("Subscription").Save(delivery).Error
+}
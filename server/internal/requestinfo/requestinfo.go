@@ -0,0 +1,50 @@
+package requestinfo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Info describes the API request a piece of work is being done for
+type Info struct {
+	// RequestID correlates logs, audit records and error responses
+	RequestID string
+	// SourceIP is the client address as resolved by the router
+	SourceIP string
+}
+
+type infoKey struct{}
+
+// WithInfo returns a copy of ctx carrying info
+func WithInfo(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// FromContext returns the request info stored in ctx, or the zero Info
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	return info
+}
+
+// ValidID accepts short request IDs made of characters safe to log
+func ValidID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewID returns a random request ID
+func NewID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
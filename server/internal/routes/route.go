@@ -2,28 +2,99 @@ package routes
 
 import (
 	"net/http"
+	"simple_bank/server/config"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
 	"simple_bank/server/internal/handler"
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/middleware"
+	"simple_bank/server/internal/openapi"
 	"simple_bank/server/internal/services"
-	"time"
+	"simple_bank/server/internal/tracing"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func SetupRouter(services *services.Services) *gin.Engine {
-	router := gin.Default()
+// apiKeyScopes maps the routes open to API keys to the scope they require
+var apiKeyScopes = map[string]auth.Scope{
+	"POST /api/v1/accounts":              auth.ScopeAccountsWrite,
+	"GET /api/v1/accounts":               auth.ScopeAccountsRead,
+	"GET /api/v1/accounts/owner/:owner":  auth.ScopeAccountsRead,
+	"GET /api/v1/accounts/:id":           auth.ScopeAccountsRead,
+	"PUT /api/v1/accounts/:id":           auth.ScopeAccountsWrite,
+	"DELETE /api/v1/accounts/:id":        auth.ScopeAccountsWrite,
+	"POST /api/v1/accounts/:id/transfer": auth.ScopeTransfersWrite,
+	"GET /api/v1/accounts/:id/transfers": auth.ScopeTransfersRead,
+	"GET /api/v1/accounts/:id/events":    auth.ScopeAccountsRead,
+	"GET /api/v1/accounts/:id/entries":   auth.ScopeAccountsRead,
+	"GET /api/v1/entries/:id":            auth.ScopeAccountsRead,
+	"GET /api/v1/transfers/:transfer_id": auth.ScopeTransfersRead,
+	// Transfer fields additionally check transfers:read
+	"POST /api/v1/graphql": auth.ScopeAccountsRead,
+}
+
+// untraced are the paths polled by infrastructure rather than clients
+var untraced = map[string]bool{
+	"/health": true,
+	"/livez":  true,
+	"/readyz": true,
+}
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "OK",
-			"time":   time.Now().Unix(),
-		})
+func SetupRouter(cfg *config.Config, services *services.Services, checker *health.Checker) *gin.Engine {
+	router := gin.New()
+	// Forwarded headers name the client only when set by a trusted proxy;
+	// otherwise anyone could choose the source IP in the audit log
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		panic(err)
+	}
+	router.Use(
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+			return !untraced[r.URL.Path]
+		})),
+		middleware.RequestInfo(),
+		middleware.AccessLog(),
+		middleware.Metrics(),
+		middleware.RenderErrors(),
+		middleware.Recover(),
+	)
+	router.NoRoute(func(c *gin.Context) {
+		_ = c.Error(apperr.New(apperr.CodeNotFound, "route not found"))
 	})
 
+	// Liveness and readiness probes
+	handler.NewHealthHandler(router, checker)
+
+	// API description and Swagger UI, readable without credentials
+	spec := openapi.MustLoad()
+	if cfg.FeatureAPIDocs {
+		handler.NewDocsHandler(router.Group("/api/v1"), spec)
+	}
+
 	// API routes
 	api := router.Group("/api/v1")
+	api.Use(
+		middleware.Authenticate(
+			auth.NewTokenAuthenticator([]byte(cfg.JWTSecret)),
+			auth.NewAPIKeyAuthenticator(services.APIKey),
+		),
+		middleware.RequireRouteScopes(apiKeyScopes),
+		middleware.ValidateRequests(spec),
+	)
 	{
 		handler.NewServicesHandler(api, services)
+		handler.NewWebhookHandler(api, services)
+		if cfg.FeatureGraphQL {
+			handler.NewGraphQLHandler(api, services)
+		}
+	}
+
+	// Admin routes
+	admin := api.Group("/admin")
+	admin.Use(middleware.RequireStaff())
+	{
+		handler.NewAdminHandler(admin, services)
+		handler.NewAPIKeyHandler(admin, services)
 	}
 
 	return router
@@ -0,0 +1,361 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"simple_bank/server/config"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/graphapi"
+	"simple_bank/server/internal/handler"
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/metrics"
+	"simple_bank/server/internal/middleware"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/openapi"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/routes"
+	"simple_bank/server/internal/services"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// undocumented are routes that serve the documentation itself
+var undocumented = map[string]bool{
+	"GET /api/v1/openapi.json":    true,
+	"GET /api/v1/docs":            true,
+	"GET /api/v1/docs/{filepath}": true,
+}
+
+// requestBodies lists the DTO each documented request body binds to
+var requestBodies = map[string]any{
+	"POST /api/v1/accounts":                          handler.CreateAccountRequest{},
+	"PUT /api/v1/accounts/{id}":                      handler.UpdateAccountRequest{},
+	"POST /api/v1/accounts/{id}/transfer":            handler.CreateTransferRequest{},
+	"PUT /api/v1/accounts/{id}/delegates/{delegate}": handler.GrantDelegateRequest{},
+	"POST /api/v1/webhooks":                          handler.SubscribeWebhookRequest{},
+	"POST /api/v1/graphql":                           graphapi.Request{},
+	"POST /api/v1/admin/accounts/{id}/freeze":        handler.AccountStatusRequest{},
+	"POST /api/v1/admin/accounts/{id}/unfreeze":      handler.AccountStatusRequest{},
+	"POST /api/v1/admin/accounts/{id}/adjustments":   handler.AdjustBalanceRequest{},
+	"POST /api/v1/admin/api-keys":                    handler.CreateAPIKeyRequest{},
+}
+
+func newRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.JWTSecret = testSecret
+	return routes.SetupRouter(cfg, &services.Services{}, health.NewChecker(time.Second))
+}
+
+func bearerToken(t *testing.T, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   subject,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// specPath converts gin's :param and *param segments to OpenAPI {param}
+func specPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func documentedOperations(doc *openapi3.T) map[string]*openapi3.Operation {
+	operations := make(map[string]*openapi3.Operation)
+	for path, item := range doc.Paths.Map() {
+		for method, operation := range item.Operations() {
+			operations[method+" "+path] = operation
+		}
+	}
+	return operations
+}
+
+func TestSpecDocumentsEveryRoute(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations := documentedOperations(doc)
+
+	served := make(map[string]bool)
+	for _, route := range newRouter(t).Routes() {
+		key := route.Method + " " + specPath(route.Path)
+		served[key] = true
+		if !undocumented[key] && operations[key] == nil {
+			t.Errorf("route %s is missing from the OpenAPI spec", key)
+		}
+	}
+	for key := range operations {
+		if !served[key] {
+			t.Errorf("OpenAPI spec documents %s, which no handler serves", key)
+		}
+	}
+}
+
+func TestSpecMatchesRequestBodies(t *testing.T) {
+	doc, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, operation := range documentedOperations(doc) {
+		dto, bound := requestBodies[key]
+		if operation.RequestBody == nil {
+			if bound {
+				t.Errorf("%s binds %T but documents no request body", key, dto)
+			}
+			continue
+		}
+		if !bound {
+			t.Errorf("%s documents a request body missing from requestBodies", key)
+			continue
+		}
+
+		schema := operation.RequestBody.Value.Content.Get("application/json").Schema.Value
+		var fields, required []string
+		dtoType := reflect.TypeOf(dto)
+		for i := range dtoType.NumField() {
+			field := dtoType.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			fields = append(fields, name)
+			if strings.Contains(field.Tag.Get("binding"), "required") {
+				required = append(required, name)
+			}
+		}
+
+		var properties []string
+		for name := range schema.Properties {
+			properties = append(properties, name)
+		}
+		slices.Sort(fields)
+		slices.Sort(properties)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("%s: %T has fields %v, spec has %v", key, dto, fields, properties)
+		}
+
+		specRequired := slices.Clone(schema.Required)
+		slices.Sort(required)
+		slices.Sort(specRequired)
+		if !slices.Equal(required, specRequired) {
+			t.Errorf("%s: %T requires %v, spec requires %v", key, dto, required, specRequired)
+		}
+	}
+}
+
+func TestInvalidRequestsAreRejected(t *testing.T) {
+	router := newRouter(t)
+	token := bearerToken(t, "alice")
+
+	cases := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/v1/accounts", `{"currency": 5}`},
+		{http.MethodPost, "/api/v1/accounts", `{"initial_balance": 10}`},
+		{http.MethodPost, "/api/v1/accounts/1/transfer", `{"to_account_id": 2, "amount": 0}`},
+		{http.MethodGet, "/api/v1/accounts/abc", ""},
+		{http.MethodGet, "/api/v1/accounts?page=first", ""},
+		{http.MethodGet, "/api/v1/accounts/1/transfers?direction=sideways", ""},
+		{http.MethodGet, "/api/v1/accounts/1/transfers?from=yesterday", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s %s: got status %d, want 400", tc.method, tc.path, tc.body, rec.Code)
+		}
+		if problem := decodeProblem(t, rec); problem.Code != apperr.CodeInvalidRequest {
+			t.Errorf("%s %s %s: got code %s, want %s", tc.method, tc.path, tc.body, problem.Code, apperr.CodeInvalidRequest)
+		}
+	}
+}
+
+func TestErrorsAreProblems(t *testing.T) {
+	router := newRouter(t)
+
+	cases := []struct {
+		path     string
+		status   int
+		code     apperr.Code
+		instance string
+	}{
+		{"/api/v1/accounts", http.StatusUnauthorized, apperr.CodeUnauthenticated, "/api/v1/accounts"},
+		{"/api/v1/accounts/owner/alice@example.com", http.StatusUnauthorized, apperr.CodeUnauthenticated, "/api/v1/accounts/owner/:owner"},
+		{"/api/v1/nowhere", http.StatusNotFound, apperr.CodeNotFound, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set(middleware.RequestIDHeader, "req-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		problem := decodeProblem(t, rec)
+		if rec.Code != tc.status || problem.Status != tc.status || problem.Code != tc.code {
+			t.Errorf("GET %s: got %d %+v, want %d %s", tc.path, rec.Code, problem, tc.status, tc.code)
+		}
+		if problem.RequestID != "req-1" || problem.Instance != tc.instance {
+			t.Errorf("GET %s: got request ID %q and instance %q", tc.path, problem.RequestID, problem.Instance)
+		}
+	}
+}
+
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) middleware.Problem {
+	t.Helper()
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, middleware.ProblemContentType) {
+		t.Errorf("got content type %q, want %s", contentType, middleware.ProblemContentType)
+	}
+	var problem middleware.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem %q: %v", rec.Body.String(), err)
+	}
+	return problem
+}
+
+func TestDocsAreServed(t *testing.T) {
+	router := newRouter(t)
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/v1/docs/", "/api/v1/docs/swagger-initializer.js"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: got status %d, want 200", path, rec.Code)
+		}
+	}
+}
+
+func TestMetricsAreNotPublic(t *testing.T) {
+	router := newRouter(t)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("GET /metrics on the API port: got status %d, want 404", rec.Code)
+	}
+
+	// They are served on the metrics port instead
+	rec = httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `simple_bank_http_request_duration_seconds_count{method="GET",route="/health",status="200"}`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("metrics do not report %s", want)
+	}
+}
+
+// auditedWrites is a unit of work that accepts every write and keeps the
+// audit records
+type auditedWrites struct {
+	logs []*models.AuditLog
+}
+
+func (w *auditedWrites) Do(ctx context.Context, fn func(tx *repositories.Repository) error) error {
+	return fn(&repositories.Repository{Account: acceptedAccounts{}, Entry: acceptedEntries{}, Outbox: acceptedEvents{}, AuditLog: w})
+}
+
+func (w *auditedWrites) Create(log *models.AuditLog) error {
+	w.logs = append(w.logs, log)
+	return nil
+}
+
+func (w *auditedWrites) List(filter repositories.AuditLogFilter, page, pageSize int) ([]models.AuditLog, error) {
+	return nil, nil
+}
+
+// acceptedAccounts, acceptedEntries and acceptedEvents accept writes and
+// drop them
+type acceptedAccounts struct{ repositories.AccountRepository }
+
+func (acceptedAccounts) Create(ctx context.Context, account *models.Account) error { return nil }
+
+type acceptedEntries struct{ repositories.EntryRepository }
+
+func (acceptedEntries) Create(ctx context.Context, entry *models.Entry) error { return nil }
+
+type acceptedEvents struct{ repositories.OutboxRepository }
+
+func (acceptedEvents) Create(event *models.OutboxEvent) error { return nil }
+
+func TestForwardedForIsIgnoredFromUntrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	writes := &auditedWrites{}
+	svc := &services.Services{
+		Account: services.NewAccountService(&repositories.Repository{}, writes, services.NewAccessPolicy(nil), services.AccountTypePolicy{}),
+	}
+	cfg := config.Default()
+	cfg.JWTSecret = testSecret
+	router := routes.SetupRouter(cfg, svc, health.NewChecker(time.Second))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts", strings.NewReader(`{"currency": "USD"}`))
+	req.RemoteAddr = "203.0.113.7:52100"
+	req.Header.Set("Authorization", "Bearer "+bearerToken(t, "alice"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(writes.logs) != 1 || writes.logs[0].SourceIP != "203.0.113.7" {
+		t.Fatalf("got audit records %+v, want one from 203.0.113.7", writes.logs)
+	}
+}
+
+// brokenKeys cannot check API keys, as when the database is down
+type brokenKeys struct {
+	services.APIKeyService
+}
+
+func (brokenKeys) VerifyAPIKey(ctx context.Context, rawKey string) (*auth.Principal, error) {
+	if rawKey == "sb_invalid_key" {
+		return nil, auth.ErrUnauthenticated
+	}
+	return nil, errors.New("connection refused")
+}
+
+func TestCredentialsThatCannotBeCheckedAreServerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.JWTSecret = testSecret
+	router := routes.SetupRouter(cfg, &services.Services{APIKey: brokenKeys{}}, health.NewChecker(time.Second))
+
+	for key, want := range map[string]int{"sb_invalid_key": http.StatusUnauthorized, "sb_some_key": http.StatusInternalServerError} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts", nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("key %s: got status %d, want %d", key, rec.Code, want)
+		}
+	}
+}
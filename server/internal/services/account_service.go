@@ -3,50 +3,87 @@ package services
 import (
 	"context"
 	"errors"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/events"
 	"simple_bank/server/internal/models"
 	"simple_bank/server/internal/repositories"
+	"slices"
+
+	"gorm.io/gorm"
 )
 
 type AccountService interface {
-	CreateAccount(ctx context.Context, owner, currency string, initialBalance int64) (*models.Account, error)
+	// CreateAccount opens an account of accountType, checking when empty
+	CreateAccount(ctx context.Context, owner, currency, accountType string, initialBalance int64) (*models.Account, error)
 	GetAccount(ctx context.Context, id int64) (*models.Account, error)
-	GetAccountsByOwner(ctx context.Context, owner string, page, pageSize int) ([]models.Account, error)
-	ListAccounts(ctx context.Context, page, pageSize int) ([]models.Account, error)
+	// GetAccountsByIDs returns the accounts the caller can view, skipping
+	// the rest, so batch loaders can fetch many accounts at once
+	GetAccountsByIDs(ctx context.Context, ids []int64) ([]models.Account, error)
+	GetAccountsByOwner(ctx context.Context, owner string, req PageRequest) (*Page[models.Account], error)
+	ListAccounts(ctx context.Context, req PageRequest) (*Page[models.Account], error)
 	UpdateAccount(ctx context.Context, id int64, balance int64) (*models.Account, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	ListDelegates(ctx context.Context, id int64) ([]models.AccountDelegate, error)
+	GrantDelegate(ctx context.Context, id int64, delegate string, canTransfer bool) (*models.AccountDelegate, error)
+	RevokeDelegate(ctx context.Context, id int64, delegate string) error
+}
+
+// AccountTypePolicy says which account types an owner may hold several
+// accounts of in the same currency. Owners hold at most one account of
+// any other type per currency.
+type AccountTypePolicy struct {
+	Multiple []string
+}
+
+// allowsMultiple reports whether accountType may have several accounts per
+// owner and currency
+func (p AccountTypePolicy) allowsMultiple(accountType string) bool {
+	return slices.Contains(p.Multiple, accountType)
 }
 
 type accountService struct {
-	repo *repositories.Repository
+	repo   *repositories.Repository
+	uow    repositories.UnitOfWork
+	policy AccessPolicy
+	types  AccountTypePolicy
 }
 
-func NewAccountService(repo *repositories.Repository) AccountService {
+func NewAccountService(repo *repositories.Repository, uow repositories.UnitOfWork, policy AccessPolicy, types AccountTypePolicy) AccountService {
 	return &accountService{
-		repo: repo,
+		repo:   repo,
+		uow:    uow,
+		policy: policy,
+		types:  types,
 	}
 }
 
-func (s *accountService) CreateAccount(ctx context.Context, owner, currency string, initialBalance int64) (*models.Account, error) {
+func (s *accountService) CreateAccount(ctx context.Context, owner, currency, accountType string, initialBalance int64) (*models.Account, error) {
 	if owner == "" {
-		return nil, errors.New("owner cannot be empty")
+		principal, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, auth.ErrUnauthenticated
+		}
+		if principal.IsService() {
+			return nil, apperr.Invalid("owner cannot be empty")
+		}
+		owner = principal.Subject
+	}
+	// Customers may only open accounts in their own name
+	if err := s.policy.AuthorizeOwner(ctx, owner); err != nil {
+		return nil, err
 	}
 	if currency == "" {
 		currency = "USD"
 	}
-	if initialBalance < 0 {
-		return nil, errors.New("initial balance cannot be negative")
+	if accountType == "" {
+		accountType = models.AccountTypeChecking
 	}
-
-	// Check if owner already has an account with this currency
-	accounts, err := s.repo.Account.GetByOwner(owner, 100, 0)
-	if err != nil {
-		return nil, err
+	if !models.ValidAccountType(accountType) {
+		return nil, apperr.Newf(apperr.CodeValidationFailed, "unknown account type %q", accountType)
 	}
-
-	for _, account := range accounts {
-		if account.Currency == currency {
-			return nil, errors.New("owner already has an account with this currency")
-		}
+	if initialBalance < 0 {
+		return nil, apperr.Invalid("initial balance cannot be negative")
 	}
 
 	// Create account instance
@@ -54,71 +91,222 @@ func (s *accountService) CreateAccount(ctx context.Context, owner, currency stri
 		Owner:    owner,
 		Balance:  initialBalance,
 		Currency: currency,
+		Type:     accountType,
+		Multiple: s.types.allowsMultiple(accountType),
 	}
 
-	// Save to database
-	err = s.repo.Account.Create(account)
+	// Open the account together with its opening entry. The unique index
+	// on owner, currency and type rejects a second account even when
+	// requests race.
+	err := s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.Account.Create(ctx, account); err != nil {
+			if isUniqueViolation(err, accountsOwnerCurrencyTypeKey) {
+				return ErrAccountExists
+			}
+			return err
+		}
+
+		// Create initial entry
+		entry := &models.Entry{
+			AccountID: account.ID, // Now account.ID is defined
+			Amount:    initialBalance,
+		}
+		if err := tx.Entry.Create(ctx, entry); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, tx.AuditLog, AuditAccountCreate, "account", account.ID, nil, account); err != nil {
+			return err
+		}
+		return publishEvent(tx.Outbox, events.AccountOpened, events.AggregateAccount, account.ID, events.AccountOpenedPayload{
+			AccountID: account.ID,
+			Owner:     account.Owner,
+			Currency:  account.Currency,
+			Balance:   account.Balance,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Create initial entry
-	entry := &models.Entry{
-		AccountID: account.ID, // Now account.ID is defined
-		Amount:    initialBalance,
+	return account, nil
+}
+
+func (s *accountService) GetAccount(ctx context.Context, id int64) (*models.Account, error) {
+	return s.authorizedAccount(ctx, id, ActionView)
+}
+
+func (s *accountService) GetAccountsByOwner(ctx context.Context, owner string, req PageRequest) (*Page[models.Account], error) {
+	if err := s.policy.AuthorizeOwner(ctx, owner); err != nil {
+		return nil, err
+	}
+
+	page, err := req.repositoryPage()
+	if err != nil {
+		return nil, err
 	}
-	err = s.repo.Entry.Create(entry)
+	accounts, err := s.repo.Account.GetByOwner(ctx, owner, page)
 	if err != nil {
-		// Rollback account creation
-		s.repo.Account.Delete(account.ID)
 		return nil, err
 	}
+	return newPage(accounts, req, accountCursor), nil
+}
 
-	return account, nil
+func (s *accountService) GetAccountsByIDs(ctx context.Context, ids []int64) ([]models.Account, error) {
+	accounts, err := s.repo.Account.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return viewableAccounts(ctx, s.policy, accounts)
 }
 
-func (s *accountService) GetAccount(ctx context.Context, id int64) (*models.Account, error) {
-	return s.repo.Account.GetByID(id)
+// ListAccounts lists the accounts owned by the caller
+func (s *accountService) ListAccounts(ctx context.Context, req PageRequest) (*Page[models.Account], error) {
+	subject, err := callerSubject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetAccountsByOwner(ctx, subject, req)
 }
 
-func (s *accountService) GetAccountsByOwner(ctx context.Context, owner string, page, pageSize int) ([]models.Account, error) {
-	if page < 1 {
-		page = 1
+// UpdateAccount sets the balance, writing the difference to the ledger as an
+// adjustment so reconciliation still balances. It is limited to staff
+// allowed to adjust balances.
+func (s *accountService) UpdateAccount(ctx context.Context, id int64, balance int64) (*models.Account, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	if !principal.Can(auth.PermBalancesAdjust) {
+		// Still hide accounts the caller cannot see
+		if _, err := s.authorizedAccount(ctx, id, ActionView); err != nil {
+			return nil, err
+		}
+		return nil, ErrForbidden
 	}
-	offset := (page - 1) * pageSize
 
-	return s.repo.Account.GetByOwner(owner, pageSize, offset)
-}
+	// The row is read under lock so that a freeze or transfer committed
+	// since the request arrived is neither lost nor audited wrongly
+	var result *models.Account
+	err := s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		account, err := lockAccount(ctx, tx.Account, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
 
-func (s *accountService) ListAccounts(ctx context.Context, page, pageSize int) ([]models.Account, error) {
-	if page < 1 {
-		page = 1
+		result = account
+		if account.Balance == balance {
+			return nil
+		}
+		if err := adjustBalance(ctx, tx, account, balance-account.Balance, AuditAccountUpdate, events.CauseOverride); err != nil {
+			return err
+		}
+		return recordAdminAction(tx.AdminAction, principal, AdminActionSetBalance, "account", id, "")
+	})
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+
+	return result, nil
+}
+
+func (s *accountService) DeleteAccount(ctx context.Context, id int64) error {
+	account, err := s.authorizedAccount(ctx, id, ActionManage)
+	if err != nil {
+		return err
 	}
+	return s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.Account.Delete(ctx, id); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx.AuditLog, AuditAccountDelete, "account", id, account, nil); err != nil {
+			return err
+		}
+		return publishEvent(tx.Outbox, events.AccountClosed, events.AggregateAccount, id, events.AccountClosedPayload{
+			AccountID: id,
+			Owner:     account.Owner,
+		})
+	})
+}
 
-	return s.repo.Account.List(page, pageSize)
+func (s *accountService) ListDelegates(ctx context.Context, id int64) ([]models.AccountDelegate, error) {
+	if _, err := s.authorizedAccount(ctx, id, ActionManage); err != nil {
+		return nil, err
+	}
+	return s.repo.Delegate.GetByAccountID(id)
 }
 
-func (s *accountService) UpdateAccount(ctx context.Context, id int64, balance int64) (*models.Account, error) {
-	account, err := s.repo.Account.GetByID(id)
+func (s *accountService) GrantDelegate(ctx context.Context, id int64, delegate string, canTransfer bool) (*models.AccountDelegate, error) {
+	account, err := s.authorizedAccount(ctx, id, ActionManage)
 	if err != nil {
 		return nil, err
 	}
+	if delegate == "" {
+		return nil, apperr.Invalid("delegate cannot be empty")
+	}
+	if delegate == account.Owner {
+		return nil, apperr.Invalid("owner cannot be a delegate of their own account")
+	}
 
-	account.Balance = balance
-	err = s.repo.Account.Update(account)
+	grant := &models.AccountDelegate{
+		AccountID:   id,
+		Delegate:    delegate,
+		CanTransfer: canTransfer,
+	}
+	err = s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		previous, err := tx.Delegate.Get(id, delegate)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Delegate.Upsert(grant); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx.AuditLog, AuditDelegateGrant, "account", id, previous, grant)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return grant, nil
+}
 
-	return account, nil
+func (s *accountService) RevokeDelegate(ctx context.Context, id int64, delegate string) error {
+	if _, err := s.authorizedAccount(ctx, id, ActionManage); err != nil {
+		return err
+	}
+
+	return s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		previous, err := tx.Delegate.Get(id, delegate)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delegate.Delete(id, delegate); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx.AuditLog, AuditDelegateRevoke, "account", id, previous, nil)
+	})
 }
 
-func (s *accountService) DeleteAccount(ctx context.Context, id int64) error {
-	return s.repo.Account.Delete(id)
+// authorizedAccount loads an account and checks the caller may perform action on it
+func (s *accountService) authorizedAccount(ctx context.Context, id int64, action Action) (*models.Account, error) {
+	account, err := s.repo.Account.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.policy.Authorize(ctx, account, action); err != nil {
+		return nil, err
+	}
+	return account, nil
 }
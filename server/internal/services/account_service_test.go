@@ -1 +1,163 @@
 package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeUnitOfWork hands work the repositories in tx and records whether
+// it would have committed
+type fakeUnitOfWork struct {
+	tx        *repositories.Repository
+	committed bool
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(tx *repositories.Repository) error) error {
+	err := fn(u.tx)
+	u.committed = err == nil
+	return err
+}
+
+type createdAccounts struct {
+	repositories.AccountRepository
+	created []*models.Account
+}
+
+func (c *createdAccounts) Create(ctx context.Context, account *models.Account) error {
+	account.ID = int64(len(c.created) + 1)
+	c.created = append(c.created, account)
+	return nil
+}
+
+type failingEntries struct {
+	repositories.EntryRepository
+}
+
+func (failingEntries) Create(ctx context.Context, entry *models.Entry) error {
+	return errors.New("connection reset")
+}
+
+func TestCreateAccountRollsBackWithItsOpeningEntry(t *testing.T) {
+	accounts := &createdAccounts{}
+	uow := &fakeUnitOfWork{tx: &repositories.Repository{Account: accounts, Entry: failingEntries{}}}
+	svc := services.NewAccountService(&repositories.Repository{}, uow, services.NewAccessPolicy(&fakeDelegates{}), services.AccountTypePolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	if _, err := svc.CreateAccount(ctx, "", "USD", "", 100); err == nil {
+		t.Fatal("expected the opening entry's error")
+	}
+	if len(accounts.created) != 1 {
+		t.Fatalf("got %d accounts created in the unit of work, want 1", len(accounts.created))
+	}
+	if uow.committed {
+		t.Error("the account was committed without its opening entry")
+	}
+}
+
+// discardedEntries, discardedAudits and discardedEvents accept writes and
+// drop them
+type discardedEntries struct{ repositories.EntryRepository }
+
+func (discardedEntries) Create(ctx context.Context, entry *models.Entry) error { return nil }
+
+type discardedAudits struct {
+	repositories.AuditLogRepository
+}
+
+func (discardedAudits) Create(log *models.AuditLog) error { return nil }
+
+type discardedEvents struct{ repositories.OutboxRepository }
+
+func (discardedEvents) Create(event *models.OutboxEvent) error { return nil }
+
+type duplicateAccounts struct {
+	repositories.AccountRepository
+}
+
+func (duplicateAccounts) Create(ctx context.Context, account *models.Account) error {
+	return &pgconn.PgError{Code: "23505", ConstraintName: "accounts_owner_currency_type_key"}
+}
+
+func TestCreateAccountReportsDuplicatesAsConflicts(t *testing.T) {
+	uow := &fakeUnitOfWork{tx: &repositories.Repository{Account: duplicateAccounts{}}}
+	svc := services.NewAccountService(&repositories.Repository{}, uow, services.NewAccessPolicy(&fakeDelegates{}), services.AccountTypePolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	_, err := svc.CreateAccount(ctx, "", "USD", "", 0)
+	if code := apperr.CodeOf(err); code != apperr.CodeConflict {
+		t.Fatalf("got code %s, want %s", code, apperr.CodeConflict)
+	}
+}
+
+func TestAccountTypePolicyAllowsMultiples(t *testing.T) {
+	accounts := &createdAccounts{}
+	uow := &fakeUnitOfWork{tx: &repositories.Repository{Account: accounts, Entry: discardedEntries{}, AuditLog: discardedAudits{}, Outbox: discardedEvents{}}}
+	svc := services.NewAccountService(&repositories.Repository{}, uow, services.NewAccessPolicy(&fakeDelegates{}),
+		services.AccountTypePolicy{Multiple: []string{models.AccountTypeSavings}})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	for _, accountType := range []string{models.AccountTypeSavings, ""} {
+		if _, err := svc.CreateAccount(ctx, "", "USD", accountType, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !accounts.created[0].Multiple || accounts.created[1].Multiple {
+		t.Errorf("only the savings account may have siblings: got %+v", accounts.created)
+	}
+	if accounts.created[1].Type != models.AccountTypeChecking {
+		t.Errorf("got type %q, want checking by default", accounts.created[1].Type)
+	}
+}
+
+// recordedEntries keeps the entries written
+type recordedEntries struct {
+	repositories.EntryRepository
+	created []*models.Entry
+}
+
+func (r *recordedEntries) Create(ctx context.Context, entry *models.Entry) error {
+	r.created = append(r.created, entry)
+	return nil
+}
+
+func TestUpdateAccountReadsTheAccountUnderLock(t *testing.T) {
+	// Only the unit of work's repositories hold the account, as a freeze
+	// committed after the request arrived
+	accounts := &ledgerAccounts{accounts: map[int64]*models.Account{
+		1: {ID: 1, Owner: "alice", Currency: "USD", Balance: 70, Status: models.AccountStatusFrozen},
+	}}
+	entries := &recordedEntries{}
+	uow := &fakeUnitOfWork{tx: &repositories.Repository{
+		Account:     accounts,
+		Entry:       entries,
+		AuditLog:    discardedAudits{},
+		Outbox:      discardedEvents{},
+		AdminAction: discardedAdminActions{},
+	}}
+	svc := services.NewAccountService(&repositories.Repository{}, uow, services.NewAccessPolicy(&fakeDelegates{}), services.AccountTypePolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ops", Role: auth.RoleAdmin})
+
+	account, err := svc.UpdateAccount(ctx, 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 100 || accounts.accounts[1].Balance != 100 {
+		t.Errorf("got balance %d, stored %d, want 100", account.Balance, accounts.accounts[1].Balance)
+	}
+	if account.Status != models.AccountStatusFrozen || !uow.committed {
+		t.Errorf("got status %q, committed %v: the freeze was lost", account.Status, uow.committed)
+	}
+	// The ledger must explain the new balance for reconciliation
+	if len(entries.created) != 1 || entries.created[0].Amount != 30 {
+		t.Errorf("got entries %+v, want one adjustment of 30", entries.created)
+	}
+}
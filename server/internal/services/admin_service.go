@@ -0,0 +1,449 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Admin action names recorded in the admin action log
+const (
+	AdminActionListAccounts    = "accounts.list"
+	AdminActionFreezeAccount   = "account.freeze"
+	AdminActionUnfreezeAccount = "account.unfreeze"
+	AdminActionAdjustBalance   = "account.adjust_balance"
+	AdminActionSetBalance      = "account.set_balance"
+	AdminActionViewTransfer    = "transfer.view"
+	AdminActionListTransfers   = "transfers.list"
+	AdminActionReverseTransfer = "transfer.reverse"
+	AdminActionReconcileLedger = "ledger.reconcile"
+	AdminActionExportAccounts  = "accounts.export"
+	AdminActionExportTransfers = "transfers.export"
+	AdminActionExportEntries   = "entries.export"
+	AdminActionCreateAPIKey    = "api_key.create"
+	AdminActionRevokeAPIKey    = "api_key.revoke"
+)
+
+// exportBatchSize is how many rows an export reads per query
+const exportBatchSize = 500
+
+// Reconciliation is the outcome of checking every balance against the
+// ledger. It is balanced when both lists are empty.
+type Reconciliation struct {
+	Accounts  []repositories.BalanceMismatch    `json:"accounts"`
+	Transfers []repositories.UnbalancedTransfer `json:"transfers"`
+}
+
+// Balanced reports whether reconciliation found nothing wrong
+func (r *Reconciliation) Balanced() bool {
+	return len(r.Accounts) == 0 && len(r.Transfers) == 0
+}
+
+// AdminService serves the operations staff API. Every method checks the
+// caller's role against the permission matrix and records the action.
+type AdminService interface {
+	ListAccounts(ctx context.Context, req PageRequest) (*Page[models.Account], error)
+	FreezeAccount(ctx context.Context, id int64, reason string) (*models.Account, error)
+	UnfreezeAccount(ctx context.Context, id int64, reason string) (*models.Account, error)
+	AdjustBalance(ctx context.Context, id int64, amount int64, reason string) (*models.Account, error)
+	GetTransfer(ctx context.Context, id int64) (*models.Transfer, error)
+	ListTransfers(ctx context.Context, req PageRequest) (*Page[models.Transfer], error)
+	// ReverseTransfer sends a transfer's amount back to its sender with a
+	// new transfer. Each transfer can be reversed once, and reversals
+	// cannot be reversed.
+	ReverseTransfer(ctx context.Context, id int64, reason string) (*models.Transfer, error)
+	// Reconcile checks every account's balance against its ledger entries
+	// and every transfer against the entries it made
+	Reconcile(ctx context.Context) (*Reconciliation, error)
+	// ExportAccounts, ExportTransfers and ExportEntries call fn with every
+	// row, oldest first, stopping at the first error
+	ExportAccounts(ctx context.Context, fn func(models.Account) error) error
+	ExportTransfers(ctx context.Context, fn func(models.Transfer) error) error
+	ExportEntries(ctx context.Context, fn func(models.Entry) error) error
+	ListActions(ctx context.Context, page, pageSize int) ([]models.AdminAction, error)
+	ListAuditLogs(ctx context.Context, filter repositories.AuditLogFilter, page, pageSize int) ([]models.AuditLog, error)
+}
+
+type adminService struct {
+	repo *repositories.Repository
+	uow  repositories.UnitOfWork
+}
+
+func NewAdminService(repo *repositories.Repository, uow repositories.UnitOfWork) AdminService {
+	return &adminService{
+		repo: repo,
+		uow:  uow,
+	}
+}
+
+func (s *adminService) ListAccounts(ctx context.Context, req PageRequest) (*Page[models.Account], error) {
+	principal, err := requirePermission(ctx, auth.PermAccountsListAll)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := req.repositoryPage()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAdminAction(s.repo.AdminAction, principal, AdminActionListAccounts, "account", 0, ""); err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.Account.List(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(accounts, req, accountCursor), nil
+}
+
+func (s *adminService) FreezeAccount(ctx context.Context, id int64, reason string) (*models.Account, error) {
+	return s.setStatus(ctx, id, models.AccountStatusFrozen, AdminActionFreezeAccount, reason)
+}
+
+func (s *adminService) UnfreezeAccount(ctx context.Context, id int64, reason string) (*models.Account, error) {
+	return s.setStatus(ctx, id, models.AccountStatusActive, AdminActionUnfreezeAccount, reason)
+}
+
+func (s *adminService) setStatus(ctx context.Context, id int64, status, action, reason string) (*models.Account, error) {
+	principal, err := requirePermission(ctx, auth.PermAccountsFreeze)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, apperr.Invalid("reason cannot be empty")
+	}
+
+	var result *models.Account
+	err = s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		account, err := tx.Account.GetForUpdate(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		before := *account
+		if err := tx.Account.UpdateStatus(ctx, id, status); err != nil {
+			return err
+		}
+		account.Status = status
+
+		auditAction, eventType := AuditAccountFreeze, events.AccountFrozen
+		if status == models.AccountStatusActive {
+			auditAction, eventType = AuditAccountUnfreeze, events.AccountUnfrozen
+		}
+		if err := recordAudit(ctx, tx.AuditLog, auditAction, "account", id, before, account); err != nil {
+			return err
+		}
+		if err := publishEvent(tx.Outbox, eventType, events.AggregateAccount, id, events.AccountStatusPayload{
+			AccountID: id,
+			Status:    status,
+			Reason:    reason,
+		}); err != nil {
+			return err
+		}
+
+		result = account
+		return recordAdminAction(tx.AdminAction, principal, action, "account", id, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// AdjustBalance credits (positive amount) or debits (negative amount) an
+// account outside of a transfer, writing a matching ledger entry
+func (s *adminService) AdjustBalance(ctx context.Context, id int64, amount int64, reason string) (*models.Account, error) {
+	principal, err := requirePermission(ctx, auth.PermBalancesAdjust)
+	if err != nil {
+		return nil, err
+	}
+	if amount == 0 {
+		return nil, apperr.Invalid("amount cannot be zero")
+	}
+	if reason == "" {
+		return nil, apperr.Invalid("reason cannot be empty")
+	}
+
+	var result *models.Account
+	err = s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		account, err := tx.Account.GetForUpdate(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := adjustBalance(ctx, tx, account, amount, AuditBalanceAdjust, events.CauseAdjustment); err != nil {
+			return err
+		}
+
+		result = account
+		return recordAdminAction(tx.AdminAction, principal, AdminActionAdjustBalance, "account", id, reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// adjustBalance moves a locked account's balance by amount, writing the
+// ledger entry, audit record and event that go with it, and updates account
+func adjustBalance(ctx context.Context, tx *repositories.Repository, account *models.Account, amount int64, auditAction, cause string) error {
+	if account.Balance+amount < 0 {
+		return apperr.Invalid("adjustment would make the balance negative")
+	}
+
+	before := *account
+	if err := tx.Account.UpdateBalance(ctx, account.ID, amount); err != nil {
+		return err
+	}
+	account.Balance += amount
+
+	entry := &models.Entry{
+		AccountID: account.ID,
+		Amount:    amount,
+		CreatedAt: time.Now(),
+	}
+	if err := tx.Entry.Create(ctx, entry); err != nil {
+		return err
+	}
+
+	if err := recordAudit(ctx, tx.AuditLog, auditAction, "account", account.ID, before, account); err != nil {
+		return err
+	}
+	return publishEvent(tx.Outbox, events.BalanceChanged, events.AggregateAccount, account.ID, events.BalanceChangedPayload{
+		AccountID: account.ID,
+		Currency:  account.Currency,
+		Amount:    amount,
+		Balance:   account.Balance,
+		Cause:     cause,
+		EntryID:   entry.ID,
+	})
+}
+
+func (s *adminService) GetTransfer(ctx context.Context, id int64) (*models.Transfer, error) {
+	principal, err := requirePermission(ctx, auth.PermTransfersViewAny)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, err := s.repo.Transfer.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAdminAction(s.repo.AdminAction, principal, AdminActionViewTransfer, "transfer", id, ""); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+func (s *adminService) ListTransfers(ctx context.Context, req PageRequest) (*Page[models.Transfer], error) {
+	principal, err := requirePermission(ctx, auth.PermTransfersViewAny)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := req.repositoryPage()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAdminAction(s.repo.AdminAction, principal, AdminActionListTransfers, "transfer", 0, ""); err != nil {
+		return nil, err
+	}
+	transfers, err := s.repo.Transfer.List(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(transfers, req, transferCursor), nil
+}
+
+func (s *adminService) ReverseTransfer(ctx context.Context, id int64, reason string) (*models.Transfer, error) {
+	principal, err := requirePermission(ctx, auth.PermTransfersReverse)
+	if err != nil {
+		return nil, err
+	}
+	if reason == "" {
+		return nil, apperr.Invalid("reason cannot be empty")
+	}
+
+	var result *models.Transfer
+	err = retryConflicts(ctx, "reverse_transfer", func() error {
+		return s.uow.Do(ctx, func(tx *repositories.Repository) error {
+			original, err := tx.Transfer.GetByID(ctx, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if original.ReversalOf != nil {
+				return apperr.Invalid("a reversal cannot be reversed")
+			}
+
+			// The original receiver sends the money back
+			from, to, err := lockPair(ctx, tx.Account, original.ToAccountID, original.FromAccountID)
+			if err != nil {
+				return err
+			}
+
+			transfer, err := moveMoney(ctx, tx, from, to, original.Amount, &original.ID)
+			if isUniqueViolation(err, transfersReversalOfKey) {
+				return ErrTransferReversed
+			}
+			if err != nil {
+				return err
+			}
+
+			result = transfer
+			return recordAdminAction(tx.AdminAction, principal, AdminActionReverseTransfer, "transfer", id, reason)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *adminService) Reconcile(ctx context.Context) (*Reconciliation, error) {
+	principal, err := requirePermission(ctx, auth.PermLedgerReconcile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordAdminAction(s.repo.AdminAction, principal, AdminActionReconcileLedger, "ledger", 0, ""); err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.Ledger.BalanceMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := s.repo.Ledger.UnbalancedTransfers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Reconciliation{Accounts: accounts, Transfers: transfers}, nil
+}
+
+func (s *adminService) ExportAccounts(ctx context.Context, fn func(models.Account) error) error {
+	if err := s.authorizeExport(ctx, AdminActionExportAccounts, "account"); err != nil {
+		return err
+	}
+	return exportAll(ctx, s.repo.Account.List, accountCursor, fn)
+}
+
+func (s *adminService) ExportTransfers(ctx context.Context, fn func(models.Transfer) error) error {
+	if err := s.authorizeExport(ctx, AdminActionExportTransfers, "transfer"); err != nil {
+		return err
+	}
+	return exportAll(ctx, s.repo.Transfer.List, transferCursor, fn)
+}
+
+func (s *adminService) ExportEntries(ctx context.Context, fn func(models.Entry) error) error {
+	if err := s.authorizeExport(ctx, AdminActionExportEntries, "entry"); err != nil {
+		return err
+	}
+	return exportAll(ctx, s.repo.Entry.List, entryCursor, fn)
+}
+
+// authorizeExport checks the caller may export data and records the export
+func (s *adminService) authorizeExport(ctx context.Context, action, targetType string) error {
+	principal, err := requirePermission(ctx, auth.PermDataExport)
+	if err != nil {
+		return err
+	}
+	return recordAdminAction(s.repo.AdminAction, principal, action, targetType, 0, "")
+}
+
+// exportAll pages through a listing oldest first, calling fn with each row
+func exportAll[T any](ctx context.Context, list func(context.Context, repositories.Page) ([]T, error), cursor func(T) repositories.Cursor, fn func(T) error) error {
+	page := repositories.Page{Limit: exportBatchSize, Ascending: true}
+	for {
+		rows, err := list(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+		after := cursor(rows[len(rows)-1])
+		page.After = &after
+	}
+}
+
+func (s *adminService) ListActions(ctx context.Context, page, pageSize int) ([]models.AdminAction, error) {
+	if _, err := requirePermission(ctx, auth.PermAdminActionsView); err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.repo.AdminAction.List(page, pageSize)
+}
+
+func (s *adminService) ListAuditLogs(ctx context.Context, filter repositories.AuditLogFilter, page, pageSize int) ([]models.AuditLog, error) {
+	if _, err := requirePermission(ctx, auth.PermAuditLogView); err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.repo.AuditLog.List(filter, page, pageSize)
+}
+
+// requirePermission returns the caller if their role grants permission
+func requirePermission(ctx context.Context, permission auth.Permission) (*auth.Principal, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	if !principal.Can(permission) {
+		return nil, ErrForbidden
+	}
+	return principal, nil
+}
+
+// recordAdminAction writes an entry to the admin action log
+func recordAdminAction(repo repositories.AdminActionRepository, principal *auth.Principal, action, targetType string, targetID int64, reason string) error {
+	return repo.Create(&models.AdminAction{
+		Actor:      principal.Subject,
+		Role:       string(principal.Role),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+	})
+}
@@ -0,0 +1,124 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// ledgerAccounts keeps balances in memory and the IDs of the accounts
+// locked, in order
+type ledgerAccounts struct {
+	repositories.AccountRepository
+	accounts map[int64]*models.Account
+	locked   []int64
+}
+
+func (l *ledgerAccounts) GetByID(ctx context.Context, id int64) (*models.Account, error) {
+	if l.accounts[id] == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	account := *l.accounts[id]
+	return &account, nil
+}
+
+func (l *ledgerAccounts) GetForUpdate(ctx context.Context, id int64) (*models.Account, error) {
+	l.locked = append(l.locked, id)
+	return l.GetByID(ctx, id)
+}
+
+func (l *ledgerAccounts) UpdateBalance(ctx context.Context, id int64, amount int64) error {
+	l.accounts[id].Balance += amount
+	return nil
+}
+
+// reversibleTransfers holds one earlier transfer and records new ones.
+// Creating fails with err when it is set.
+type reversibleTransfers struct {
+	repositories.TransferRepository
+	original *models.Transfer
+	created  []*models.Transfer
+	err      error
+}
+
+func (r *reversibleTransfers) GetByID(ctx context.Context, id int64) (*models.Transfer, error) {
+	return r.original, nil
+}
+
+func (r *reversibleTransfers) Create(ctx context.Context, transfer *models.Transfer) error {
+	if r.err != nil {
+		return r.err
+	}
+	transfer.ID = r.original.ID + int64(len(r.created)) + 1
+	r.created = append(r.created, transfer)
+	return nil
+}
+
+type discardedAdminActions struct {
+	repositories.AdminActionRepository
+}
+
+func (discardedAdminActions) Create(action *models.AdminAction) error { return nil }
+
+func newReversal(transfers *reversibleTransfers) (services.AdminService, *ledgerAccounts) {
+	accounts := &ledgerAccounts{accounts: map[int64]*models.Account{
+		1: {ID: 1, Owner: "alice", Currency: "USD", Balance: 70},
+		2: {ID: 2, Owner: "bob", Currency: "USD", Balance: 30},
+	}}
+	uow := &fakeUnitOfWork{tx: &repositories.Repository{
+		Account:     accounts,
+		Transfer:    transfers,
+		Entry:       discardedEntries{},
+		AuditLog:    discardedAudits{},
+		Outbox:      discardedEvents{},
+		AdminAction: discardedAdminActions{},
+	}}
+	return services.NewAdminService(&repositories.Repository{}, uow), accounts
+}
+
+func TestReverseTransferSendsTheAmountBack(t *testing.T) {
+	transfers := &reversibleTransfers{original: &models.Transfer{ID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 30}}
+	svc, accounts := newReversal(transfers)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ops", Role: auth.RoleAdmin})
+
+	reversal, err := svc.ReverseTransfer(ctx, 7, "sent to the wrong account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reversal.FromAccountID != 2 || reversal.ToAccountID != 1 || reversal.Amount != 30 {
+		t.Errorf("got reversal %+v, want 30 from account 2 to account 1", reversal)
+	}
+	if reversal.ReversalOf == nil || *reversal.ReversalOf != 7 {
+		t.Errorf("got reversal of %v, want transfer 7", reversal.ReversalOf)
+	}
+	if accounts.accounts[1].Balance != 100 || accounts.accounts[2].Balance != 0 {
+		t.Errorf("got balances %d and %d, want 100 and 0", accounts.accounts[1].Balance, accounts.accounts[2].Balance)
+	}
+}
+
+func TestReverseTransferOnlyOnce(t *testing.T) {
+	reversed := &reversibleTransfers{
+		original: &models.Transfer{ID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 30},
+		err:      &pgconn.PgError{Code: "23505", ConstraintName: "transfers_reversal_of_key"},
+	}
+	svc, _ := newReversal(reversed)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ops", Role: auth.RoleAdmin})
+	if _, err := svc.ReverseTransfer(ctx, 7, "again"); !errors.Is(err, services.ErrTransferReversed) {
+		t.Errorf("got %v reversing a reversed transfer, want %v", err, services.ErrTransferReversed)
+	}
+
+	reversalOf := int64(6)
+	reversal := &reversibleTransfers{original: &models.Transfer{ID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 30, ReversalOf: &reversalOf}}
+	svc, _ = newReversal(reversal)
+	if _, err := svc.ReverseTransfer(ctx, 7, "undo"); err == nil || len(reversal.created) != 0 {
+		t.Errorf("reversed a reversal: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix marks a string as a simple bank API key
+const apiKeyPrefix = "sbk"
+
+var ErrAPIKeyNotFound = apperr.New(apperr.CodeAPIKeyNotFound, "api key not found")
+
+type APIKeyService interface {
+	auth.APIKeyVerifier
+	// CreateAPIKey issues a key and returns it with its plaintext value,
+	// which is never stored and cannot be retrieved again
+	CreateAPIKey(ctx context.Context, name string, scopes []auth.Scope, accountIDs []int64, expiresAt *time.Time) (*models.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, page, pageSize int) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+}
+
+type apiKeyService struct {
+	repo *repositories.Repository
+	uow  repositories.UnitOfWork
+}
+
+func NewAPIKeyService(repo *repositories.Repository, uow repositories.UnitOfWork) APIKeyService {
+	return &apiKeyService{
+		repo: repo,
+		uow:  uow,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, name string, scopes []auth.Scope, accountIDs []int64, expiresAt *time.Time) (*models.APIKey, string, error) {
+	principal, err := requirePermission(ctx, auth.PermAPIKeysManage)
+	if err != nil {
+		return nil, "", err
+	}
+	if name == "" {
+		return nil, "", apperr.Invalid("name cannot be empty")
+	}
+	if len(scopes) == 0 {
+		return nil, "", apperr.Invalid("at least one scope is required")
+	}
+	scopeNames := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, "", apperr.Newf(apperr.CodeValidationFailed, "unknown scope %q", scope)
+		}
+		scopeNames = append(scopeNames, string(scope))
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", apperr.Invalid("expiry must be in the future")
+	}
+	if accountIDs == nil {
+		accountIDs = []int64{}
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	prefix = apiKeyPrefix + "_" + prefix
+	rawKey := prefix + "_" + secret
+
+	key := &models.APIKey{
+		Name:       name,
+		Prefix:     prefix,
+		KeyHash:    hashAPIKey(rawKey),
+		Scopes:     scopeNames,
+		AccountIDs: accountIDs,
+		CreatedBy:  principal.Subject,
+		ExpiresAt:  expiresAt,
+	}
+	err = s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.APIKey.Create(key); err != nil {
+			return err
+		}
+		if err := recordAdminAction(tx.AdminAction, principal, AdminActionCreateAPIKey, "api_key", key.ID, name); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx.AuditLog, AuditAPIKeyCreate, "api_key", key.ID, nil, key)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, page, pageSize int) ([]models.APIKey, error) {
+	if _, err := requirePermission(ctx, auth.PermAPIKeysManage); err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.repo.APIKey.List(page, pageSize)
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, id int64) error {
+	principal, err := requirePermission(ctx, auth.PermAPIKeysManage)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.repo.APIKey.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.APIKey.Revoke(id, time.Now()); err != nil {
+			return err
+		}
+		if err := recordAdminAction(tx.AdminAction, principal, AdminActionRevokeAPIKey, "api_key", id, ""); err != nil {
+			return err
+		}
+
+		revoked, err := tx.APIKey.GetByID(id)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx.AuditLog, AuditAPIKeyRevoke, "api_key", id, key, revoked)
+	})
+}
+
+// VerifyAPIKey looks the key up by its prefix and compares hashes in
+// constant time. Unknown, revoked and expired keys are all rejected alike.
+func (s *apiKeyService) VerifyAPIKey(ctx context.Context, rawKey string) (*auth.Principal, error) {
+	idx := strings.LastIndex(rawKey, "_")
+	if idx <= 0 || !strings.HasPrefix(rawKey, apiKeyPrefix+"_") {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	key, err := s.repo.APIKey.GetByPrefix(rawKey[:idx])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, auth.ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up api key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(rawKey)), []byte(key.KeyHash)) != 1 {
+		return nil, auth.ErrUnauthenticated
+	}
+	if !key.Active(time.Now()) {
+		return nil, auth.ErrUnauthenticated
+	}
+
+	scopes := make([]auth.Scope, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, auth.Scope(scope))
+	}
+
+	return &auth.Principal{
+		Subject:    "apikey:" + key.Prefix,
+		APIKeyID:   key.ID,
+		Scopes:     scopes,
+		AccountIDs: key.AccountIDs,
+	}, nil
+}
+
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
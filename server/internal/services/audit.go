@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/requestinfo"
+)
+
+// Audited mutations
+const (
+	AuditAccountCreate   = "account.create"
+	AuditAccountUpdate   = "account.update"
+	AuditAccountDelete   = "account.delete"
+	AuditAccountFreeze   = "account.freeze"
+	AuditAccountUnfreeze = "account.unfreeze"
+	AuditBalanceAdjust   = "account.adjust_balance"
+	AuditDelegateGrant   = "account.delegate_grant"
+	AuditDelegateRevoke  = "account.delegate_revoke"
+	AuditTransferCreate  = "transfer.create"
+	AuditTransferReverse = "transfer.reverse"
+	AuditAPIKeyCreate    = "api_key.create"
+	AuditAPIKeyRevoke    = "api_key.revoke"
+)
+
+// recordAudit appends a mutation to the audit log. Pass a repository bound
+// to the mutation's transaction so the record commits or rolls back with it.
+// before and after are marshalled to JSON; nil means the record did not
+// exist on that side of the change.
+func recordAudit(ctx context.Context, repo repositories.AuditLogRepository, action, targetType string, targetID int64, before, after any) error {
+	actor := ""
+	if principal, ok := auth.FromContext(ctx); ok {
+		actor = principal.Subject
+	}
+	info := requestinfo.FromContext(ctx)
+
+	beforeSnapshot, err := models.NewJSON(before)
+	if err != nil {
+		return err
+	}
+	afterSnapshot, err := models.NewJSON(after)
+	if err != nil {
+		return err
+	}
+
+	return repo.Create(&models.AuditLog{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     beforeSnapshot,
+		After:      afterSnapshot,
+		RequestID:  info.RequestID,
+		SourceIP:   info.SourceIP,
+	})
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+var ErrEntryNotFound = apperr.New(apperr.CodeEntryNotFound, "entry not found")
+
+type EntryService interface {
+	// GetEntry returns an entry of an account the caller can view, with its
+	// running balance
+	GetEntry(ctx context.Context, id int64) (*models.Entry, error)
+	// ListEntries pages through an account's entries that match filter,
+	// with their running balances, counting all that match
+	ListEntries(ctx context.Context, accountID int64, filter repositories.EntryFilter, req PageRequest) (*Page[models.Entry], error)
+	// ListEntriesForAccounts returns a page of the ledger entries of each
+	// account the caller can view, keyed by account ID. Pages follow
+	// cursors only; req.Page is ignored.
+	ListEntriesForAccounts(ctx context.Context, accountIDs []int64, req PageRequest) (map[int64]*Page[models.Entry], error)
+}
+
+type entryService struct {
+	repo   *repositories.Repository
+	policy AccessPolicy
+}
+
+func NewEntryService(repo *repositories.Repository, policy AccessPolicy) EntryService {
+	return &entryService{
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+func (s *entryService) GetEntry(ctx context.Context, id int64) (*models.Entry, error) {
+	entry, err := s.repo.Entry.GetWithBalanceByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries of accounts the caller cannot view do not exist for them
+	err = s.policy.Authorize(ctx, &entry.Account, ActionView)
+	if errors.Is(err, ErrAccountNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *entryService) ListEntries(ctx context.Context, accountID int64, filter repositories.EntryFilter, req PageRequest) (*Page[models.Entry], error) {
+	account, err := s.repo.Account.GetByID(ctx, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(ctx, account, ActionView); err != nil {
+		return nil, err
+	}
+
+	if err := validateDirection(filter.Direction); err != nil {
+		return nil, err
+	}
+	if err := validateAmounts(filter.MinAmount, filter.MaxAmount); err != nil {
+		return nil, err
+	}
+	switch filter.Type {
+	case "", repositories.EntryTypeTransfer, repositories.EntryTypeAdjustment:
+	default:
+		return nil, apperr.Invalid("type must be transfer or adjustment")
+	}
+	page, err := req.repositoryPage(repositories.SortAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.repo.Entry.ListWithBalanceByAccountID(ctx, accountID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Entry.CountByAccountID(ctx, accountID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newPage(entries, req, entryCursor)
+	result.Total = total
+	return result, nil
+}
+
+func (s *entryService) ListEntriesForAccounts(ctx context.Context, accountIDs []int64, req PageRequest) (map[int64]*Page[models.Entry], error) {
+	page, err := req.repositoryPage(repositories.SortAmount)
+	if err != nil {
+		return nil, err
+	}
+	viewable, err := viewableAccountIDs(ctx, s.repo.Account, s.policy, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(viewable) == 0 {
+		return map[int64]*Page[models.Entry]{}, nil
+	}
+
+	byAccount, err := s.repo.Entry.ListByAccountIDs(ctx, viewable, page)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[int64]*Page[models.Entry], len(viewable))
+	for _, id := range viewable {
+		pages[id] = newPage(byAccount[id], req, entryCursor)
+	}
+	return pages, nil
+}
@@ -0,0 +1,72 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"gorm.io/gorm"
+)
+
+type fakeEntries struct {
+	repositories.EntryRepository
+	entries []models.Entry
+}
+
+func (f *fakeEntries) GetWithBalanceByID(ctx context.Context, id int64) (*models.Entry, error) {
+	for i := range f.entries {
+		if f.entries[i].ID == id {
+			return &f.entries[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func TestGetEntryHidesOtherAccounts(t *testing.T) {
+	repo := &repositories.Repository{Entry: &fakeEntries{entries: []models.Entry{
+		{ID: 1, AccountID: 1, Amount: 100, Account: models.Account{ID: 1, Owner: "alice"}},
+	}}}
+	svc := services.NewEntryService(repo, services.NewAccessPolicy(&fakeDelegates{}))
+
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+	if _, err := svc.GetEntry(alice, 1); err != nil {
+		t.Fatalf("GetEntry() by owner = %v, want nil", err)
+	}
+
+	mallory := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "mallory"})
+	if _, err := svc.GetEntry(mallory, 1); !errors.Is(err, services.ErrEntryNotFound) {
+		t.Fatalf("GetEntry() by stranger = %v, want %v", err, services.ErrEntryNotFound)
+	}
+	if _, err := svc.GetEntry(alice, 2); !errors.Is(err, services.ErrEntryNotFound) {
+		t.Fatalf("GetEntry() of missing entry = %v, want %v", err, services.ErrEntryNotFound)
+	}
+}
+
+func TestListingsRejectInvalidAmountBounds(t *testing.T) {
+	repo := &repositories.Repository{Account: aliceAccount{}}
+	policy := services.NewAccessPolicy(&fakeDelegates{})
+	entries, transfers := services.NewEntryService(repo, policy), services.NewTransferService(repo, nil, policy)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	amount := func(n int64) *int64 { return &n }
+	for name, bounds := range map[string][2]*int64{
+		"negative minimum": {amount(-1), nil},
+		"negative maximum": {nil, amount(-5)},
+		"minimum above":    {amount(10), amount(5)},
+	} {
+		_, err := entries.ListEntries(ctx, 1, repositories.EntryFilter{MinAmount: bounds[0], MaxAmount: bounds[1]}, services.PageRequest{})
+		if code := apperr.CodeOf(err); code != apperr.CodeValidationFailed {
+			t.Errorf("entries with %s: got %v, want %s", name, err, apperr.CodeValidationFailed)
+		}
+		_, err = transfers.ListTransfers(ctx, 1, repositories.TransferFilter{MinAmount: bounds[0], MaxAmount: bounds[1]}, services.PageRequest{})
+		if code := apperr.CodeOf(err); code != apperr.CodeValidationFailed {
+			t.Errorf("transfers with %s: got %v, want %s", name, err, apperr.CodeValidationFailed)
+		}
+	}
+}
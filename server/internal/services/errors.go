@@ -0,0 +1,45 @@
+package services
+
+import (
+	"errors"
+
+	"simple_bank/server/internal/apperr"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrAccountNotFound  = apperr.New(apperr.CodeAccountNotFound, "account not found")
+	ErrTransferNotFound = apperr.New(apperr.CodeTransferNotFound, "transfer not found")
+	// ErrTransferReversed is returned when reversing a transfer twice
+	ErrTransferReversed = apperr.New(apperr.CodeConflict, "transfer has already been reversed")
+	ErrAccountFrozen    = apperr.New(apperr.CodeAccountFrozen, "account is frozen")
+	// ErrAccountExists is returned when the owner already has an account of
+	// the requested type and currency
+	ErrAccountExists     = apperr.New(apperr.CodeConflict, "owner already has an account of this type with this currency")
+	ErrInsufficientFunds = apperr.New(apperr.CodeInsufficientFunds, "insufficient balance")
+	ErrCurrencyMismatch  = apperr.New(apperr.CodeCurrencyMismatch, "accounts have different currencies")
+	// ErrForbidden is returned when the caller may see a resource but not
+	// perform the requested action on it
+	ErrForbidden = apperr.New(apperr.CodeForbidden, "not allowed to perform this action")
+	// ErrResumeExpired is returned when a stream is resumed from further back
+	// than the missed events it replays
+	ErrResumeExpired = apperr.New(apperr.CodeResumeExpired, "too many events were missed to resume; reload the account and subscribe without a last event ID")
+	// ErrInvalidCursor is returned for a page cursor this server did not issue
+	ErrInvalidCursor = apperr.New(apperr.CodeInvalidRequest, "invalid cursor")
+)
+
+// accountsOwnerCurrencyTypeKey is the unique index that keeps owners to one
+// account per currency and type
+const accountsOwnerCurrencyTypeKey = "accounts_owner_currency_type_key"
+
+// transfersReversalOfKey is the unique constraint that lets a transfer be
+// reversed only once
+const transfersReversalOfKey = "transfers_reversal_of_key"
+
+// isUniqueViolation reports whether err was raised by the unique constraint
+// or index named constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
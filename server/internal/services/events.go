@@ -0,0 +1,18 @@
+package services
+
+import (
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/repositories"
+)
+
+// publishEvent writes a domain event to the outbox. Pass a repository bound
+// to the change's transaction so the event is only relayed if it commits,
+// and write it after locking or changing the aggregate's row so that the
+// aggregate's events commit in ID order.
+func publishEvent(repo repositories.OutboxRepository, eventType, aggregateType string, aggregateID int64, payload any) error {
+	event, err := events.NewEvent(eventType, aggregateType, aggregateID, payload)
+	if err != nil {
+		return err
+	}
+	return repo.Create(event)
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/base64"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+)
+
+// PageRequest selects a page of a listing. Cursor, the NextCursor of a
+// previous page, takes precedence over Page, which is kept for clients
+// that still page by number.
+type PageRequest struct {
+	Page     int
+	PageSize int
+	Cursor   string
+	// Sort defaults to created_at, newest first unless Ascending is set
+	Sort      repositories.SortField
+	Ascending bool
+}
+
+// Page is one page of a listing. NextCursor is empty on the last page.
+// Total counts the matching items across all pages; only filtered
+// listings set it.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+	Total      int64
+	cursor     func(T) string
+}
+
+// CursorAt returns the cursor of the page that continues after the i-th
+// item, for APIs such as GraphQL that hand out a cursor per item
+func (p *Page[T]) CursorAt(i int) string {
+	if p.cursor == nil {
+		return ""
+	}
+	return p.cursor(p.Items[i])
+}
+
+// normalize applies the default page, page size and sort
+func (r PageRequest) normalize() PageRequest {
+	if r.Page < 1 {
+		r.Page = 1
+	}
+	if r.PageSize < 1 || r.PageSize > 100 {
+		r.PageSize = 10
+	}
+	if r.Sort == "" {
+		r.Sort = repositories.SortCreatedAt
+	}
+	return r
+}
+
+// repositoryPage translates the request, fetching one extra row so that
+// newPage can tell whether another page follows. sortable lists the
+// fields the listing may be sorted by besides created_at.
+func (r PageRequest) repositoryPage(sortable ...repositories.SortField) (repositories.Page, error) {
+	r = r.normalize()
+	if r.Sort != repositories.SortCreatedAt && !slices.Contains(sortable, r.Sort) {
+		return repositories.Page{}, apperr.New(apperr.CodeInvalidRequest, "cannot sort by "+string(r.Sort))
+	}
+
+	page := repositories.Page{
+		Limit:     r.PageSize + 1,
+		Offset:    (r.Page - 1) * r.PageSize,
+		Sort:      r.Sort,
+		Ascending: r.Ascending,
+	}
+	if r.Cursor != "" {
+		after, err := decodeCursor(r.Cursor, r.Sort, r.Ascending)
+		if err != nil {
+			return repositories.Page{}, err
+		}
+		page.After = after
+	}
+	return page, nil
+}
+
+// newPage trims the extra row fetched by repositoryPage and, if there was
+// one, points NextCursor at the last row returned
+func newPage[T any](items []T, r PageRequest, cursor func(T) repositories.Cursor) *Page[T] {
+	r = r.normalize()
+	page := &Page[T]{Items: items, cursor: func(item T) string {
+		return encodeCursor(cursor(item), r.Sort, r.Ascending)
+	}}
+	if len(items) > r.PageSize {
+		page.Items = items[:r.PageSize]
+		page.NextCursor = page.CursorAt(r.PageSize - 1)
+	}
+	return page
+}
+
+// Cursors are opaque to clients. They encode the sort of the listing and
+// the sort value and ID of the last row of a page, so a cursor cannot be
+// reused with a different sort.
+func encodeCursor(c repositories.Cursor, sort repositories.SortField, ascending bool) string {
+	value := c.CreatedAt.UTC().Format(time.RFC3339Nano)
+	if sort == repositories.SortAmount {
+		value = strconv.FormatInt(c.Amount, 10)
+	}
+	raw := strings.Join([]string{sortKey(sort, ascending), value, strconv.FormatInt(c.ID, 10)}, ",")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string, sort repositories.SortField, ascending bool) (*repositories.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ",")
+	if len(parts) != 3 || parts[0] != sortKey(sort, ascending) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c repositories.Cursor
+	if sort == repositories.SortAmount {
+		c.Amount, err = strconv.ParseInt(parts[1], 10, 64)
+	} else {
+		c.CreatedAt, err = time.Parse(time.RFC3339Nano, parts[1])
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// sortKey names a sort in a cursor, such as "-created_at" for newest first
+func sortKey(sort repositories.SortField, ascending bool) string {
+	if ascending {
+		return string(sort)
+	}
+	return "-" + string(sort)
+}
+
+// validateDirection rejects directions other than incoming and outgoing
+func validateDirection(direction repositories.Direction) error {
+	switch direction {
+	case "", repositories.DirectionIncoming, repositories.DirectionOutgoing:
+		return nil
+	}
+	return apperr.Invalid("direction must be incoming or outgoing")
+}
+
+// validateAmounts rejects negative amount bounds and a minimum above the
+// maximum
+func validateAmounts(minAmount, maxAmount *int64) error {
+	if minAmount != nil && *minAmount < 0 || maxAmount != nil && *maxAmount < 0 {
+		return apperr.Invalid("min_amount and max_amount cannot be negative")
+	}
+	if minAmount != nil && maxAmount != nil && *minAmount > *maxAmount {
+		return apperr.Invalid("min_amount cannot be greater than max_amount")
+	}
+	return nil
+}
+
+func accountCursor(account models.Account) repositories.Cursor {
+	return repositories.Cursor{CreatedAt: account.CreatedAt, ID: account.ID}
+}
+
+func transferCursor(transfer models.Transfer) repositories.Cursor {
+	return repositories.Cursor{CreatedAt: transfer.CreatedAt, Amount: transfer.Amount, ID: transfer.ID}
+}
+
+// entryCursor holds the size of the amount, which entries are sorted by
+func entryCursor(entry models.Entry) repositories.Cursor {
+	return repositories.Cursor{CreatedAt: entry.CreatedAt, Amount: max(entry.Amount, -entry.Amount), ID: entry.ID}
+}
@@ -0,0 +1,116 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+)
+
+// pagedAccounts serves GetByOwner from memory, ordered newest first, the
+// way the database applies a repositories.Page
+type pagedAccounts struct {
+	repositories.AccountRepository
+	accounts []models.Account
+}
+
+func (p *pagedAccounts) GetByOwner(ctx context.Context, owner string, page repositories.Page) ([]models.Account, error) {
+	var matched []models.Account
+	for _, account := range p.accounts {
+		if page.After != nil {
+			after := account.CreatedAt.Before(page.After.CreatedAt) ||
+				account.CreatedAt.Equal(page.After.CreatedAt) && account.ID < page.After.ID
+			if !after {
+				continue
+			}
+		}
+		matched = append(matched, account)
+	}
+	if page.After == nil {
+		matched = matched[min(page.Offset, len(matched)):]
+	}
+	return matched[:min(page.Limit, len(matched))], nil
+}
+
+func TestCursorPagination(t *testing.T) {
+	// Newest first; 2 and 3 share a timestamp so the ID breaks the tie
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &pagedAccounts{accounts: []models.Account{
+		{ID: 5, Owner: "alice", CreatedAt: created.Add(3 * time.Second)},
+		{ID: 4, Owner: "alice", CreatedAt: created.Add(2 * time.Second)},
+		{ID: 3, Owner: "alice", CreatedAt: created.Add(time.Second)},
+		{ID: 2, Owner: "alice", CreatedAt: created.Add(time.Second)},
+		{ID: 1, Owner: "alice", CreatedAt: created},
+	}}
+	svc := services.NewAccountService(&repositories.Repository{Account: repo}, nil, services.NewAccessPolicy(&fakeDelegates{}), services.AccountTypePolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	var seen []int64
+	req := services.PageRequest{PageSize: 2}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, err := svc.ListAccounts(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, account := range page.Items {
+			seen = append(seen, account.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+
+	if want := []int64{5, 4, 3, 2, 1}; !slices.Equal(seen, want) {
+		t.Fatalf("saw accounts %v, want %v", seen, want)
+	}
+}
+
+func TestCursorAtContinuesAfterEachItem(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &pagedAccounts{accounts: []models.Account{
+		{ID: 3, Owner: "alice", CreatedAt: created.Add(2 * time.Second)},
+		{ID: 2, Owner: "alice", CreatedAt: created.Add(time.Second)},
+		{ID: 1, Owner: "alice", CreatedAt: created},
+	}}
+	svc := services.NewAccountService(&repositories.Repository{Account: repo}, nil, services.NewAccessPolicy(&fakeDelegates{}), services.AccountTypePolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	page, err := svc.ListAccounts(ctx, services.PageRequest{PageSize: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, err := svc.ListAccounts(ctx, services.PageRequest{PageSize: 3, Cursor: page.CursorAt(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest.Items) != 2 || rest.Items[0].ID != 2 {
+		t.Errorf("after the first item got %+v, want accounts 2 and 1", rest.Items)
+	}
+
+	// A cursor continues only the order it was issued for
+	if _, err := svc.ListAccounts(ctx, services.PageRequest{Cursor: page.CursorAt(0), Ascending: true}); !errors.Is(err, services.ErrInvalidCursor) {
+		t.Errorf("got %v reusing a cursor in the other order, want %v", err, services.ErrInvalidCursor)
+	}
+}
+
+func TestInvalidCursor(t *testing.T) {
+	svc := services.NewAccountService(&repositories.Repository{Account: &pagedAccounts{}}, nil, services.NewAccessPolicy(&fakeDelegates{}), services.AccountTypePolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	for _, cursor := range []string{"not base64!", "bm8tY29tbWE", "eWVzdGVyZGF5LDE"} {
+		_, err := svc.ListAccounts(ctx, services.PageRequest{Cursor: cursor})
+		if !errors.Is(err, services.ErrInvalidCursor) {
+			t.Errorf("cursor %q: got %v, want %v", cursor, err, services.ErrInvalidCursor)
+		}
+	}
+}
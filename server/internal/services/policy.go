@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Action is an operation a caller wants to perform on an account
+type Action int
+
+const (
+	// ActionView covers reading the account and its transfers
+	ActionView Action = iota
+	// ActionTransfer covers sending money from the account
+	ActionTransfer
+	// ActionManage covers updating, deleting and delegating the account
+	ActionManage
+)
+
+// AccessPolicy decides whether the caller in ctx may act on an account.
+//
+// Callers who cannot even view an account get ErrAccountNotFound so that
+// the existence of other people's accounts is not revealed. Callers who can
+// view it but lack the requested right get ErrForbidden.
+type AccessPolicy interface {
+	Authorize(ctx context.Context, account *models.Account, action Action) error
+	// AuthorizeOwner checks the caller may open or list accounts for owner
+	AuthorizeOwner(ctx context.Context, owner string) error
+}
+
+type accessPolicy struct {
+	delegates repositories.DelegateRepository
+}
+
+func NewAccessPolicy(delegates repositories.DelegateRepository) AccessPolicy {
+	return &accessPolicy{delegates: delegates}
+}
+
+func (p *accessPolicy) Authorize(ctx context.Context, account *models.Account, action Action) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	// API keys act for services; route scopes were checked by middleware
+	if principal.IsService() {
+		if !principal.CanAccessAccount(account.ID) {
+			return ErrAccountNotFound
+		}
+		return nil
+	}
+
+	// Owners may do anything with their own accounts, and staff allowed
+	// to operate accounts may do what owners can
+	if principal.Subject == account.Owner || principal.Can(auth.PermAccountsOperate) {
+		return nil
+	}
+
+	grant, err := p.delegates.Get(account.ID, principal.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case ActionView:
+		return nil
+	case ActionTransfer:
+		if grant.CanTransfer {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+func (p *accessPolicy) AuthorizeOwner(ctx context.Context, owner string) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+
+	// Keys restricted to specific accounts cannot act across an owner
+	if principal.IsService() && len(principal.AccountIDs) == 0 {
+		return nil
+	}
+	if principal.Subject == owner || principal.Can(auth.PermAccountsOperate) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// viewableAccounts keeps the accounts the caller in ctx may view, dropping
+// the rest as if they did not exist
+func viewableAccounts(ctx context.Context, policy AccessPolicy, accounts []models.Account) ([]models.Account, error) {
+	viewable := accounts[:0:0]
+	for i := range accounts {
+		err := policy.Authorize(ctx, &accounts[i], ActionView)
+		if errors.Is(err, ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		viewable = append(viewable, accounts[i])
+	}
+	return viewable, nil
+}
+
+// viewableAccountIDs keeps the IDs of existing accounts the caller may view
+func viewableAccountIDs(ctx context.Context, repo repositories.AccountRepository, policy AccessPolicy, ids []int64) ([]int64, error) {
+	accounts, err := repo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err = viewableAccounts(ctx, policy, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	viewable := make([]int64, len(accounts))
+	for i, account := range accounts {
+		viewable[i] = account.ID
+	}
+	return viewable, nil
+}
+
+// callerSubject returns the subject of the authenticated caller
+func callerSubject(ctx context.Context) (string, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return "", auth.ErrUnauthenticated
+	}
+	return principal.Subject, nil
+}
@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/services"
+
+	"gorm.io/gorm"
+)
+
+type fakeDelegates struct {
+	grants []models.AccountDelegate
+}
+
+func (f *fakeDelegates) Upsert(delegate *models.AccountDelegate) error { return nil }
+
+func (f *fakeDelegates) Get(accountID int64, delegate string) (*models.AccountDelegate, error) {
+	for i := range f.grants {
+		if f.grants[i].AccountID == accountID && f.grants[i].Delegate == delegate {
+			return &f.grants[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeDelegates) GetByAccountID(accountID int64) ([]models.AccountDelegate, error) {
+	return nil, nil
+}
+
+func (f *fakeDelegates) Delete(accountID int64, delegate string) error { return nil }
+
+func TestAccessPolicyAuthorize(t *testing.T) {
+	policy := services.NewAccessPolicy(&fakeDelegates{grants: []models.AccountDelegate{
+		{AccountID: 1, Delegate: "viewer"},
+		{AccountID: 1, Delegate: "payer", CanTransfer: true},
+	}})
+	account := &models.Account{ID: 1, Owner: "alice"}
+
+	tests := []struct {
+		name    string
+		subject string
+		action  services.Action
+		want    error
+	}{
+		{"owner can manage", "alice", services.ActionManage, nil},
+		{"viewer can view", "viewer", services.ActionView, nil},
+		{"viewer cannot transfer", "viewer", services.ActionTransfer, services.ErrForbidden},
+		{"payer can transfer", "payer", services.ActionTransfer, nil},
+		{"payer cannot manage", "payer", services.ActionManage, services.ErrForbidden},
+		{"stranger sees nothing", "mallory", services.ActionView, services.ErrAccountNotFound},
+		{"stranger cannot transfer", "mallory", services.ActionTransfer, services.ErrAccountNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: tt.subject})
+			err := policy.Authorize(ctx, account, tt.action)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Authorize() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessPolicyLetsAdminsOperateAccounts(t *testing.T) {
+	policy := services.NewAccessPolicy(&fakeDelegates{})
+	account := &models.Account{ID: 1, Owner: "alice"}
+
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "ops", Role: auth.RoleAdmin})
+	if err := policy.Authorize(admin, account, services.ActionTransfer); err != nil {
+		t.Errorf("admin Authorize() = %v, want nil", err)
+	}
+	if err := policy.AuthorizeOwner(admin, "alice"); err != nil {
+		t.Errorf("admin AuthorizeOwner() = %v, want nil", err)
+	}
+
+	support := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "helpdesk", Role: auth.RoleSupport})
+	if err := policy.Authorize(support, account, services.ActionView); !errors.Is(err, services.ErrAccountNotFound) {
+		t.Errorf("support Authorize() = %v, want %v", err, services.ErrAccountNotFound)
+	}
+}
+
+func TestAccessPolicyRequiresPrincipal(t *testing.T) {
+	policy := services.NewAccessPolicy(&fakeDelegates{})
+	err := policy.Authorize(context.Background(), &models.Account{ID: 1, Owner: "alice"}, services.ActionView)
+	if !errors.Is(err, auth.ErrUnauthenticated) {
+		t.Fatalf("Authorize() = %v, want %v", err, auth.ErrUnauthenticated)
+	}
+}
+
+func TestAccessPolicyAPIKeyAllowList(t *testing.T) {
+	policy := services.NewAccessPolicy(&fakeDelegates{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject:    "apikey:sbk_test",
+		APIKeyID:   7,
+		AccountIDs: []int64{1},
+	})
+
+	if err := policy.Authorize(ctx, &models.Account{ID: 1, Owner: "alice"}, services.ActionTransfer); err != nil {
+		t.Fatalf("Authorize() on allowed account = %v, want nil", err)
+	}
+	err := policy.Authorize(ctx, &models.Account{ID: 2, Owner: "alice"}, services.ActionView)
+	if !errors.Is(err, services.ErrAccountNotFound) {
+		t.Fatalf("Authorize() on other account = %v, want %v", err, services.ErrAccountNotFound)
+	}
+	if err := policy.AuthorizeOwner(ctx, "alice"); !errors.Is(err, services.ErrForbidden) {
+		t.Fatalf("AuthorizeOwner() for restricted key = %v, want %v", err, services.ErrForbidden)
+	}
+}
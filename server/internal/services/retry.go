@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"simple_bank/server/internal/metrics"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// maxTransactionAttempts bounds how often a transaction aborted by a
+// serialization failure is run
+const maxTransactionAttempts = 3
+
+// retryConflicts runs fn, a whole transaction, again when Postgres aborted
+// it with a serialization failure, which leaves no changes behind.
+// Deadlocks are not retried: accounts are locked in ID order, so one is a
+// bug to surface rather than hide. operation labels the retry metric.
+func retryConflicts(ctx context.Context, operation string, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if attempt == maxTransactionAttempts || !isConflict(err) || ctx.Err() != nil {
+			return err
+		}
+		metrics.TransactionRetries.WithLabelValues(operation).Inc()
+	}
+}
+
+// isConflict reports whether err is a serialization failure
+func isConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
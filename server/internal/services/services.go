@@ -2,18 +2,29 @@ package services
 
 import (
 	"simple_bank/server/internal/repositories"
-
-	"gorm.io/gorm"
+	"simple_bank/server/internal/stream"
 )
 
 type Services struct {
 	Account  AccountService
+	Entry    EntryService
 	Transfer TransferService
+	APIKey   APIKeyService
+	Admin    AdminService
+	Webhook  WebhookService
+	Stream   StreamService
 }
 
-func NewServices(repo *repositories.Repository, db *gorm.DB) *Services {
+func NewServices(repo *repositories.Repository, uow repositories.UnitOfWork, broker *stream.Broker, accountTypes AccountTypePolicy, webhookTargets WebhookPolicy) *Services {
+	policy := NewAccessPolicy(repo.Delegate)
+
 	return &Services{
-		Account:  NewAccountService(repo),
-		Transfer: NewTransferService(repo, db),
+		Account:  NewAccountService(repo, uow, policy, accountTypes),
+		Entry:    NewEntryService(repo, policy),
+		Transfer: NewTransferService(repo, uow, policy),
+		APIKey:   NewAPIKeyService(repo, uow),
+		Admin:    NewAdminService(repo, uow),
+		Webhook:  NewWebhookService(repo, uow, policy, webhookTargets),
+		Stream:   NewStreamService(repo, broker, policy),
 	}
 }
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/stream"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// maxBackfill bounds how many missed events a resuming subscriber replays.
+// Subscribers further behind get ErrResumeExpired rather than a partial
+// backlog.
+const maxBackfill = 1000
+
+type StreamService interface {
+	// SubscribeAccount streams live updates for an account the caller can
+	// view. Messages after lastMessageID that were missed are returned as
+	// a backlog; the caller must skip live messages with IDs up to the last
+	// one it sent. Resuming from more than maxBackfill events back fails
+	// with ErrResumeExpired.
+	SubscribeAccount(ctx context.Context, accountID, lastMessageID int64) ([]stream.Message, *stream.Subscription, error)
+}
+
+type streamService struct {
+	repo   *repositories.Repository
+	broker *stream.Broker
+	policy AccessPolicy
+}
+
+func NewStreamService(repo *repositories.Repository, broker *stream.Broker, policy AccessPolicy) StreamService {
+	return &streamService{
+		repo:   repo,
+		broker: broker,
+		policy: policy,
+	}
+}
+
+func (s *streamService) SubscribeAccount(ctx context.Context, accountID, lastMessageID int64) ([]stream.Message, *stream.Subscription, error) {
+	account, err := s.repo.Account.GetByID(ctx, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.policy.Authorize(ctx, account, ActionView); err != nil {
+		return nil, nil, err
+	}
+
+	// Subscribe before reading the backlog so nothing published in between is lost
+	sub := s.broker.Subscribe(accountID)
+	if lastMessageID <= 0 {
+		return nil, sub, nil
+	}
+
+	// An account's events commit in ID order, because every writer holds
+	// the account's row lock while it adds them (see events.Relay). Once
+	// an event was delivered no lower ID of this account can commit, so it
+	// is a committed watermark and nothing below it can have been missed.
+	// The last message's event is read again, as the client may have seen
+	// only part of it; one more is read to tell a full backfill from more.
+	missed, err := s.repo.Outbox.GetByAggregate(events.AggregateAccount, accountID, stream.EventID(lastMessageID)-1, maxBackfill+2)
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	var backlog []stream.Message
+	replayed := 0
+	for _, event := range missed {
+		unseen := slices.DeleteFunc(stream.Messages(event), func(msg stream.Message) bool { return msg.ID <= lastMessageID })
+		if len(unseen) > 0 {
+			replayed++
+			backlog = append(backlog, unseen...)
+		}
+	}
+	if replayed > maxBackfill {
+		sub.Close()
+		return nil, nil, ErrResumeExpired
+	}
+	return backlog, sub, nil
+}
@@ -0,0 +1,96 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+	"simple_bank/server/internal/stream"
+)
+
+// missedEvents is an account whose events run up to lastID, each
+// producing one status message
+type missedEvents struct {
+	repositories.OutboxRepository
+	lastID int64
+}
+
+func (o missedEvents) GetByAggregate(aggregateType string, aggregateID, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	payload, _ := json.Marshal(events.AccountStatusPayload{AccountID: aggregateID})
+	var missed []models.OutboxEvent
+	for id := afterID + 1; id <= o.lastID && len(missed) < limit; id++ {
+		missed = append(missed, models.OutboxEvent{ID: id, EventType: events.AccountFrozen, AggregateType: aggregateType, AggregateID: aggregateID, Payload: payload})
+	}
+	return missed, nil
+}
+
+func TestSubscribeAccountReplaysOrExpiresTheBacklog(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+	// The client saw the message of event 10
+	last := stream.Messages(models.OutboxEvent{ID: 10, EventType: events.AccountFrozen, AggregateType: events.AggregateAccount, AggregateID: 1})[0].ID
+
+	for missed, wantErr := range map[int]apperr.Code{0: "", 3: "", 1000: "", 1001: apperr.CodeResumeExpired} {
+		repo := &repositories.Repository{Account: aliceAccount{}, Outbox: missedEvents{lastID: 10 + int64(missed)}}
+		svc := services.NewStreamService(repo, stream.NewBroker(), services.NewAccessPolicy(&fakeDelegates{}))
+
+		backlog, sub, err := svc.SubscribeAccount(ctx, 1, last)
+		if wantErr != "" {
+			if code := apperr.CodeOf(err); code != wantErr || sub != nil {
+				t.Errorf("%d missed: got %v (%s), want %s", missed, err, code, wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d missed: %v", missed, err)
+		}
+		sub.Close()
+		if len(backlog) != missed {
+			t.Errorf("%d missed: replayed %d messages", missed, len(backlog))
+		}
+		if missed > 0 && (stream.EventID(backlog[0].ID) != 11 || stream.EventID(backlog[len(backlog)-1].ID) != int64(10+missed)) {
+			t.Errorf("%d missed: replayed events %d to %d", missed, stream.EventID(backlog[0].ID), stream.EventID(backlog[len(backlog)-1].ID))
+		}
+	}
+}
+
+// balanceChange is an account whose only event changed its balance
+type balanceChange struct {
+	repositories.OutboxRepository
+	event models.OutboxEvent
+}
+
+func (o balanceChange) GetByAggregate(aggregateType string, aggregateID, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	if o.event.ID <= afterID {
+		return nil, nil
+	}
+	return []models.OutboxEvent{o.event}, nil
+}
+
+func TestSubscribeAccountResumesWithinAnEvent(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+	event, err := events.NewEvent(events.BalanceChanged, events.AggregateAccount, 1, events.BalanceChangedPayload{AccountID: 1, Amount: 5, Balance: 5, EntryID: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.ID = 10
+	entry, balance := stream.Messages(*event)[0], stream.Messages(*event)[1]
+
+	repo := &repositories.Repository{Account: aliceAccount{}, Outbox: balanceChange{event: *event}}
+	svc := services.NewStreamService(repo, stream.NewBroker(), services.NewAccessPolicy(&fakeDelegates{}))
+
+	// The client disconnected between the entry and the balance
+	backlog, sub, err := svc.SubscribeAccount(ctx, 1, entry.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+	if len(backlog) != 1 || backlog[0].ID != balance.ID {
+		t.Errorf("replayed %+v, want only the balance", backlog)
+	}
+}
@@ -3,144 +3,353 @@ package services
 import (
 	"context"
 	"errors"
-	"fmt"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/metrics"
 	"simple_bank/server/internal/models"
 	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/tracing"
 	"time"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type TransferService interface {
 	CreateTransfer(ctx context.Context, fromAccountID, toAccountID, amount int64) (*models.Transfer, error)
 	GetTransfer(ctx context.Context, id int64) (*models.Transfer, error)
-	ListTransfers(ctx context.Context, accountID int64, page, pageSize int) ([]models.Transfer, error)
+	// GetTransfersByIDs returns the transfers the caller can view, skipping
+	// the rest
+	GetTransfersByIDs(ctx context.Context, ids []int64) ([]models.Transfer, error)
+	// ListTransfers pages through the transfers an account sent or
+	// received that match filter, counting all that match
+	ListTransfers(ctx context.Context, accountID int64, filter repositories.TransferFilter, req PageRequest) (*Page[models.Transfer], error)
+	// ListTransfersForAccounts returns a page of the transfers of each
+	// account the caller can view, keyed by account ID. Pages follow
+	// cursors only; req.Page is ignored.
+	ListTransfersForAccounts(ctx context.Context, accountIDs []int64, req PageRequest) (map[int64]*Page[models.Transfer], error)
 }
 
 type transferService struct {
-	repo *repositories.Repository
-	db   *gorm.DB
+	repo   *repositories.Repository
+	uow    repositories.UnitOfWork
+	policy AccessPolicy
 }
 
-func NewTransferService(repo *repositories.Repository, db *gorm.DB) TransferService {
+func NewTransferService(repo *repositories.Repository, uow repositories.UnitOfWork, policy AccessPolicy) TransferService {
 	return &transferService{
-		repo: repo,
-		db:   db,
+		repo:   repo,
+		uow:    uow,
+		policy: policy,
 	}
 }
 
 // CreateTransfer performs a money transfer between two accounts
-func (s *transferService) CreateTransfer(ctx context.Context, fromAccountID, toAccountID, amount int64) (*models.Transfer, error) {
+func (s *transferService) CreateTransfer(ctx context.Context, fromAccountID, toAccountID, amount int64) (result *models.Transfer, err error) {
+	ctx, span := tracing.Start(ctx, "TransferService.CreateTransfer",
+		tracing.FromAccountIDKey.Int64(fromAccountID),
+		tracing.ToAccountIDKey.Int64(toAccountID),
+		tracing.AmountBucketKey.String(tracing.AmountBucket(amount)),
+	)
+	var currency string
+	defer func() {
+		metrics.ObserveTransfer(currency, amount, err)
+		if result != nil {
+			span.SetAttributes(tracing.TransferIDKey.Int64(result.ID), tracing.CurrencyKey.String(currency))
+		}
+		tracing.End(span, err)
+	}()
+
 	if amount <= 0 {
-		return nil, errors.New("amount must be positive")
+		return nil, apperr.Invalid("amount must be positive")
 	}
 
 	if fromAccountID == toAccountID {
-		return nil, errors.New("cannot transfer to the same account")
+		return nil, apperr.Invalid("cannot transfer to the same account")
 	}
 
-	var result *models.Transfer
-
-	// Use transaction to ensure data consistency
-	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// Get both accounts with FOR UPDATE lock
-		var fromAccount, toAccount models.Account
-
-		// Lock from account
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			First(&fromAccount, fromAccountID).Error; err != nil {
+	transact := func(tx *repositories.Repository) error {
+		// Only the owner or a delegate allowed to transfer may send money.
+		// The account is checked before it is locked, so that callers who
+		// may not use it cannot hold its lock and stall its transfers.
+		fromAccount, err := tx.Account.GetByID(ctx, fromAccountID)
+		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("from account not found")
+				return ErrAccountNotFound
 			}
 			return err
 		}
+		if err := s.policy.Authorize(ctx, fromAccount, ActionTransfer); err != nil {
+			return err
+		}
 
-		// Lock to account
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			First(&toAccount, toAccountID).Error; err != nil {
+		fromAccount, toAccount, err := lockPair(ctx, tx.Account, fromAccountID, toAccountID)
+		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("to account not found")
+				return apperr.New(apperr.CodeAccountNotFound, "to account not found")
 			}
 			return err
 		}
 
-		// Check if accounts have the same currency
-		if fromAccount.Currency != toAccount.Currency {
-			return errors.New("accounts have different currencies")
+		currency = fromAccount.Currency
+		transfer, err := moveMoney(ctx, tx, fromAccount, toAccount, amount, nil)
+		if err != nil {
+			return err
 		}
 
-		// Check if from account has sufficient balance
-		if fromAccount.Balance < amount {
-			return errors.New("insufficient balance")
-		}
+		result = transfer
+		return nil
+	}
 
-		// Update balances
-		if err := tx.Model(&fromAccount).
-			Update("balance", gorm.Expr("balance - ?", amount)).Error; err != nil {
-			return err
-		}
+	// Use transaction to ensure data consistency
+	err = retryConflicts(ctx, "create_transfer", func() error {
+		return s.uow.Do(ctx, transact)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		if err := tx.Model(&toAccount).
-			Update("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
-			return err
-		}
+	return result, nil
+}
 
-		// Create transfer record
-		transfer := &models.Transfer{
-			FromAccountID: fromAccountID,
-			ToAccountID:   toAccountID,
-			Amount:        amount,
-			CreatedAt:     time.Now(),
-		}
+// moveMoney sends amount from one locked account to another, recording the
+// transfer, its entries, audit record and events in tx. reversalOf is set
+// when the transfer sends back an earlier one.
+func moveMoney(ctx context.Context, tx *repositories.Repository, from, to *models.Account, amount int64, reversalOf *int64) (*models.Transfer, error) {
+	// Frozen accounts can neither send nor receive money
+	if from.Frozen() || to.Frozen() {
+		return nil, ErrAccountFrozen
+	}
 
-		if err := tx.Create(transfer).Error; err != nil {
-			return err
+	// Check if accounts have the same currency
+	if from.Currency != to.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	// Check if from account has sufficient balance
+	if from.Balance < amount {
+		return nil, ErrInsufficientFunds
+	}
+
+	before := map[string]any{
+		"from_account": *from,
+		"to_account":   *to,
+	}
+
+	// Update balances
+	if err := tx.Account.UpdateBalance(ctx, from.ID, -amount); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Account.UpdateBalance(ctx, to.ID, amount); err != nil {
+		return nil, err
+	}
+
+	// Create transfer record
+	transfer := &models.Transfer{
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		ReversalOf:    reversalOf,
+		CreatedAt:     time.Now(),
+	}
+
+	if err := tx.Transfer.Create(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	// Create entry for from account (negative amount)
+	fromEntry := &models.Entry{
+		AccountID:  from.ID,
+		Amount:     -amount,
+		TransferID: &transfer.ID,
+		CreatedAt:  time.Now(),
+	}
+	if err := tx.Entry.Create(ctx, fromEntry); err != nil {
+		return nil, err
+	}
+
+	// Create entry for to account (positive amount)
+	toEntry := &models.Entry{
+		AccountID:  to.ID,
+		Amount:     amount,
+		TransferID: &transfer.ID,
+		CreatedAt:  time.Now(),
+	}
+	if err := tx.Entry.Create(ctx, toEntry); err != nil {
+		return nil, err
+	}
+
+	from.Balance -= amount
+	to.Balance += amount
+	after := map[string]any{
+		"transfer":     transfer,
+		"from_account": from,
+		"to_account":   to,
+	}
+	auditAction, cause := AuditTransferCreate, events.CauseTransfer
+	payload := events.TransferCompletedPayload{
+		TransferID:    transfer.ID,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		Currency:      from.Currency,
+	}
+	if reversalOf != nil {
+		auditAction, cause = AuditTransferReverse, events.CauseReversal
+		payload.ReversalOf = *reversalOf
+	}
+	if err := recordAudit(ctx, tx.AuditLog, auditAction, "transfer", transfer.ID, before, after); err != nil {
+		return nil, err
+	}
+
+	if err := publishEvent(tx.Outbox, events.TransferCompleted, events.AggregateTransfer, transfer.ID, payload); err != nil {
+		return nil, err
+	}
+	for _, change := range []struct {
+		account *models.Account
+		entry   *models.Entry
+	}{{from, fromEntry}, {to, toEntry}} {
+		if err := publishEvent(tx.Outbox, events.BalanceChanged, events.AggregateAccount, change.account.ID, events.BalanceChangedPayload{
+			AccountID:  change.account.ID,
+			Currency:   change.account.Currency,
+			Amount:     change.entry.Amount,
+			Balance:    change.account.Balance,
+			Cause:      cause,
+			EntryID:    change.entry.ID,
+			TransferID: transfer.ID,
+		}); err != nil {
+			return nil, err
 		}
+	}
 
-		// Create entry for from account (negative amount)
-		fromEntry := &models.Entry{
-			AccountID: fromAccountID,
-			Amount:    -amount,
-			CreatedAt: time.Now(),
+	return transfer, nil
+}
+
+// lockPair locks two accounts in ascending ID order, so that transfers and
+// reversals between them in either direction wait for each other instead
+// of deadlocking. The accounts are returned in the order asked for.
+func lockPair(ctx context.Context, accounts repositories.AccountRepository, a, b int64) (*models.Account, *models.Account, error) {
+	first, second := min(a, b), max(a, b)
+	lockedFirst, err := lockAccount(ctx, accounts, first)
+	if err != nil {
+		return nil, nil, err
+	}
+	lockedSecond, err := lockAccount(ctx, accounts, second)
+	if err != nil {
+		return nil, nil, err
+	}
+	if first == a {
+		return lockedFirst, lockedSecond, nil
+	}
+	return lockedSecond, lockedFirst, nil
+}
+
+// lockAccount loads an account FOR UPDATE, recording the time spent
+// waiting for the lock
+func lockAccount(ctx context.Context, accounts repositories.AccountRepository, id int64) (*models.Account, error) {
+	start := time.Now()
+	defer func() { metrics.LockWait.Observe(time.Since(start).Seconds()) }()
+	return accounts.GetForUpdate(ctx, id)
+}
+
+// GetTransfer returns a transfer the caller can view through either of its accounts
+func (s *transferService) GetTransfer(ctx context.Context, id int64) (*models.Transfer, error) {
+	transfer, err := s.repo.Transfer.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range []*models.Account{&transfer.FromAccount, &transfer.ToAccount} {
+		err := s.policy.Authorize(ctx, account, ActionView)
+		if err == nil {
+			return transfer, nil
 		}
-		if err := tx.Create(fromEntry).Error; err != nil {
-			return err
+		if !errors.Is(err, ErrAccountNotFound) {
+			return nil, err
 		}
+	}
+	return nil, ErrTransferNotFound
+}
 
-		// Create entry for to account (positive amount)
-		toEntry := &models.Entry{
-			AccountID: toAccountID,
-			Amount:    amount,
-			CreatedAt: time.Now(),
+func (s *transferService) GetTransfersByIDs(ctx context.Context, ids []int64) ([]models.Transfer, error) {
+	transfers, err := s.repo.Transfer.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	viewable := transfers[:0]
+	for _, transfer := range transfers {
+		accounts, err := viewableAccounts(ctx, s.policy, []models.Account{transfer.FromAccount, transfer.ToAccount})
+		if err != nil {
+			return nil, err
 		}
-		if err := tx.Create(toEntry).Error; err != nil {
-			return err
+		if len(accounts) > 0 {
+			viewable = append(viewable, transfer)
 		}
+	}
+	return viewable, nil
+}
 
-		result = transfer
-		return nil
-	})
+func (s *transferService) ListTransfers(ctx context.Context, accountID int64, filter repositories.TransferFilter, req PageRequest) (*Page[models.Transfer], error) {
+	account, err := s.repo.Account.GetByID(ctx, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.policy.Authorize(ctx, account, ActionView); err != nil {
+		return nil, err
+	}
 
+	if err := validateDirection(filter.Direction); err != nil {
+		return nil, err
+	}
+	if err := validateAmounts(filter.MinAmount, filter.MaxAmount); err != nil {
+		return nil, err
+	}
+	page, err := req.repositoryPage(repositories.SortAmount)
 	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
-}
+	transfers, err := s.repo.Transfer.GetByAccountID(ctx, accountID, filter, page)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.Transfer.CountByAccountID(ctx, accountID, filter)
+	if err != nil {
+		return nil, err
+	}
 
-func (s *transferService) GetTransfer(ctx context.Context, id int64) (*models.Transfer, error) {
-	return s.repo.Transfer.GetByID(id)
+	result := newPage(transfers, req, transferCursor)
+	result.Total = total
+	return result, nil
 }
 
-func (s *transferService) ListTransfers(ctx context.Context, accountID int64, page, pageSize int) ([]models.Transfer, error) {
-	if page < 1 {
-		page = 1
+func (s *transferService) ListTransfersForAccounts(ctx context.Context, accountIDs []int64, req PageRequest) (map[int64]*Page[models.Transfer], error) {
+	page, err := req.repositoryPage(repositories.SortAmount)
+	if err != nil {
+		return nil, err
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	viewable, err := viewableAccountIDs(ctx, s.repo.Account, s.policy, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(viewable) == 0 {
+		return map[int64]*Page[models.Transfer]{}, nil
 	}
 
-	offset := (page - 1) * pageSize
-	return s.repo.Transfer.GetByAccountID(accountID, pageSize, offset)
+	byAccount, err := s.repo.Transfer.ListByAccountIDs(ctx, viewable, page)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[int64]*Page[models.Transfer], len(viewable))
+	for _, id := range viewable {
+		pages[id] = newPage(byAccount[id], req, transferCursor)
+	}
+	return pages, nil
 }
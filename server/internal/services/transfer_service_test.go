@@ -0,0 +1,111 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// conflictingUnitOfWork aborts its first conflicts attempts with code, the
+// way Postgres aborts a transaction, leaving no changes behind
+type conflictingUnitOfWork struct {
+	fakeUnitOfWork
+	code      string
+	conflicts int
+	attempts  int
+}
+
+func (u *conflictingUnitOfWork) Do(ctx context.Context, fn func(tx *repositories.Repository) error) error {
+	u.attempts++
+	if u.attempts <= u.conflicts {
+		return &pgconn.PgError{Code: u.code, Message: "could not serialize access"}
+	}
+	return u.fakeUnitOfWork.Do(ctx, fn)
+}
+
+func newTransfers(conflicts int) (services.TransferService, *conflictingUnitOfWork, *ledgerAccounts) {
+	accounts := &ledgerAccounts{accounts: map[int64]*models.Account{
+		1: {ID: 1, Owner: "alice", Currency: "USD", Balance: 70},
+		2: {ID: 2, Owner: "bob", Currency: "USD", Balance: 30},
+	}}
+	uow := &conflictingUnitOfWork{code: "40001", conflicts: conflicts, fakeUnitOfWork: fakeUnitOfWork{tx: &repositories.Repository{
+		Account:  accounts,
+		Transfer: &reversibleTransfers{original: &models.Transfer{}},
+		Entry:    discardedEntries{},
+		AuditLog: discardedAudits{},
+		Outbox:   discardedEvents{},
+	}}}
+	return services.NewTransferService(&repositories.Repository{}, uow, services.NewAccessPolicy(&fakeDelegates{})), uow, accounts
+}
+
+func TestCreateTransferRetriesSerializationFailures(t *testing.T) {
+	svc, uow, accounts := newTransfers(2)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	if _, err := svc.CreateTransfer(ctx, 1, 2, 20); err != nil {
+		t.Fatal(err)
+	}
+	if uow.attempts != 3 {
+		t.Errorf("ran the transaction %d times, want 3", uow.attempts)
+	}
+	if accounts.accounts[1].Balance != 50 || accounts.accounts[2].Balance != 50 {
+		t.Errorf("got balances %d and %d, want 50 and 50", accounts.accounts[1].Balance, accounts.accounts[2].Balance)
+	}
+}
+
+func TestCreateTransferStopsRetrying(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	// Three serialization failures in a row are reported
+	svc, uow, _ := newTransfers(3)
+	var pgErr *pgconn.PgError
+	if _, err := svc.CreateTransfer(ctx, 1, 2, 20); !errors.As(err, &pgErr) || uow.attempts != 3 {
+		t.Errorf("got %v after %d attempts, want the serialization failure after 3", err, uow.attempts)
+	}
+
+	// Deadlocks are not hidden by retrying
+	svc, uow, _ = newTransfers(1)
+	uow.code = "40P01"
+	if _, err := svc.CreateTransfer(ctx, 1, 2, 20); !errors.As(err, &pgErr) || pgErr.Code != "40P01" || uow.attempts != 1 {
+		t.Errorf("got %v after %d attempts, want the deadlock after 1", err, uow.attempts)
+	}
+
+	// Other failures are not retried
+	svc, uow, _ = newTransfers(0)
+	if _, err := svc.CreateTransfer(ctx, 1, 2, 100); !errors.Is(err, services.ErrInsufficientFunds) || uow.attempts != 1 {
+		t.Errorf("got %v after %d attempts, want %v after 1", err, uow.attempts, services.ErrInsufficientFunds)
+	}
+}
+
+func TestCreateTransferAuthorizesBeforeLocking(t *testing.T) {
+	svc, _, accounts := newTransfers(0)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "mallory"})
+
+	if _, err := svc.CreateTransfer(ctx, 1, 2, 20); err == nil {
+		t.Fatal("mallory sent money from alice's account")
+	}
+	if len(accounts.locked) != 0 {
+		t.Errorf("locked accounts %v for a caller who may not use them", accounts.locked)
+	}
+}
+
+func TestTransfersLockAccountsInIDOrder(t *testing.T) {
+	svc, _, accounts := newTransfers(0)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "bob"})
+
+	// bob's account 2 sends to 1, yet 1 is locked first like in a transfer from 1 to 2
+	if _, err := svc.CreateTransfer(ctx, 2, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(accounts.locked, []int64{1, 2}) {
+		t.Errorf("locked accounts %v, want [1 2]", accounts.locked)
+	}
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/webhooks"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookNotFound  = apperr.New(apperr.CodeWebhookNotFound, "webhook subscription not found")
+	ErrDeliveryNotFound = apperr.New(apperr.CodeDeliveryNotFound, "webhook delivery not found")
+)
+
+// Audited webhook mutations
+const (
+	AuditWebhookSubscribe   = "webhook.subscribe"
+	AuditWebhookUnsubscribe = "webhook.unsubscribe"
+	AuditDeliveryReplay     = "webhook.replay"
+)
+
+// AdminActionReplayDelivery is recorded when staff replay a delivery
+const AdminActionReplayDelivery = "webhook_delivery.replay"
+
+type WebhookService interface {
+	// Subscribe registers a URL for events on an account the caller can
+	// view and returns the signing secret, which is only shown once
+	Subscribe(ctx context.Context, accountID int64, rawURL string, eventTypes []string, balanceThreshold *int64) (*models.WebhookSubscription, string, error)
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	Unsubscribe(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, status string, page, pageSize int) ([]models.WebhookDelivery, error)
+	// ReplayDelivery requeues a delivery, typically a dead-lettered one
+	ReplayDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	// CanDeliver reports whether a subscription's owner may still view its
+	// account. The webhook sink checks it before queueing each delivery,
+	// so owners and delegates who lose access stop receiving events.
+	CanDeliver(ctx context.Context, subscription *models.WebhookSubscription) (bool, error)
+}
+
+// WebhookPolicy says where webhooks may be sent
+type WebhookPolicy struct {
+	// AllowPrivateNetworks accepts URLs on loopback, private and link-local
+	// addresses, for local development only
+	AllowPrivateNetworks bool
+}
+
+type webhookService struct {
+	repo    *repositories.Repository
+	uow     repositories.UnitOfWork
+	policy  AccessPolicy
+	targets WebhookPolicy
+}
+
+func NewWebhookService(repo *repositories.Repository, uow repositories.UnitOfWork, policy AccessPolicy, targets WebhookPolicy) WebhookService {
+	return &webhookService{
+		repo:    repo,
+		uow:     uow,
+		policy:  policy,
+		targets: targets,
+	}
+}
+
+func (s *webhookService) Subscribe(ctx context.Context, accountID int64, rawURL string, eventTypes []string, balanceThreshold *int64) (*models.WebhookSubscription, string, error) {
+	subject, err := callerSubject(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	account, err := s.repo.Account.GetByID(ctx, accountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.policy.Authorize(ctx, account, ActionView); err != nil {
+		return nil, "", err
+	}
+
+	if err := s.validateURL(ctx, rawURL); err != nil {
+		return nil, "", err
+	}
+	if len(eventTypes) == 0 {
+		return nil, "", apperr.Invalid("at least one event type is required")
+	}
+	for _, eventType := range eventTypes {
+		if !webhooks.ValidEventType(eventType) {
+			return nil, "", apperr.Newf(apperr.CodeValidationFailed, "unknown event type %q", eventType)
+		}
+		if eventType == webhooks.EventBalanceBelowThreshold && balanceThreshold == nil {
+			return nil, "", apperr.Invalid("balance_threshold is required for balance.below_threshold")
+		}
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	secret = "whsec_" + secret
+
+	subscription := &models.WebhookSubscription{
+		Owner:            subject,
+		AccountID:        accountID,
+		URL:              rawURL,
+		Secret:           secret,
+		EventTypes:       eventTypes,
+		BalanceThreshold: balanceThreshold,
+	}
+	err = s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.Webhook.Create(subscription); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx.AuditLog, AuditWebhookSubscribe, "webhook_subscription", subscription.ID, nil, subscription)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return subscription, secret, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subject, err := callerSubject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Webhook.GetByOwner(subject)
+}
+
+func (s *webhookService) Unsubscribe(ctx context.Context, id int64) error {
+	subject, err := callerSubject(ctx)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := s.repo.Webhook.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	// Hide other callers' subscriptions
+	if subscription.Owner != subject {
+		return ErrWebhookNotFound
+	}
+
+	return s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.Webhook.Delete(id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx.AuditLog, AuditWebhookUnsubscribe, "webhook_subscription", id, subscription, nil)
+	})
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, status string, page, pageSize int) ([]models.WebhookDelivery, error) {
+	if _, err := requirePermission(ctx, auth.PermWebhooksManage); err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	return s.repo.Delivery.List(status, page, pageSize)
+}
+
+func (s *webhookService) ReplayDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	principal, err := requirePermission(ctx, auth.PermWebhooksManage)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.Delivery.GetByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	before := *delivery
+	delivery.Status = models.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.LastError = ""
+	delivery.DeliveredAt = nil
+	err = s.uow.Do(ctx, func(tx *repositories.Repository) error {
+		if err := tx.Delivery.Update(delivery); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, tx.AuditLog, AuditDeliveryReplay, "webhook_delivery", id, before, delivery); err != nil {
+			return err
+		}
+		return recordAdminAction(tx.AdminAction, principal, AdminActionReplayDelivery, "webhook_delivery", id, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *webhookService) CanDeliver(ctx context.Context, subscription *models.WebhookSubscription) (bool, error) {
+	account, err := s.repo.Account.GetByID(ctx, subscription.AccountID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Roles are not kept with subscriptions, so owners are checked as
+	// customers: only the account's owner and delegates receive events
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: subscription.Owner})
+	err = s.policy.Authorize(ctx, account, ActionView)
+	if errors.Is(err, ErrAccountNotFound) || errors.Is(err, ErrForbidden) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// validateURL accepts absolute http and https URLs whose host resolves to
+// public addresses only. The dispatcher checks the addresses again when it
+// connects, since DNS answers can change.
+func (s *webhookService) validateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return apperr.Invalid("url must be an absolute http or https URL")
+	}
+	if s.targets.AllowPrivateNetworks {
+		return nil
+	}
+
+	err = webhooks.CheckHost(ctx, u.Hostname())
+	if errors.Is(err, webhooks.ErrPrivateAddress) {
+		return apperr.Invalid("url must not point to a loopback, private or link-local address")
+	}
+	if err != nil {
+		return apperr.Invalid("url host cannot be resolved")
+	}
+	return nil
+}
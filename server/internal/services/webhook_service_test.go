@@ -0,0 +1,63 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/auth"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/services"
+	"simple_bank/server/internal/webhooks"
+)
+
+// aliceAccount is account 1, owned by alice
+type aliceAccount struct {
+	repositories.AccountRepository
+}
+
+func (aliceAccount) GetByID(ctx context.Context, id int64) (*models.Account, error) {
+	return &models.Account{ID: id, Owner: "alice", Currency: "USD"}, nil
+}
+
+func TestSubscribeRejectsPrivateURLs(t *testing.T) {
+	repo := &repositories.Repository{Account: aliceAccount{}}
+	svc := services.NewWebhookService(repo, &fakeUnitOfWork{}, services.NewAccessPolicy(&fakeDelegates{}), services.WebhookPolicy{})
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+
+	for _, url := range []string{
+		"http://127.0.0.1/hook",
+		"http://localhost:8080/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"https://10.0.0.5/hook",
+		"https://192.168.1.10/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		_, _, err := svc.Subscribe(ctx, 1, url, []string{webhooks.EventTransferReceived}, nil)
+		if code := apperr.CodeOf(err); code != apperr.CodeValidationFailed {
+			t.Errorf("Subscribe(%s): got %v (%s), want %s", url, err, code, apperr.CodeValidationFailed)
+		}
+	}
+}
+
+func TestCanDeliverStopsOnceAccessIsRevoked(t *testing.T) {
+	delegates := &fakeDelegates{grants: []models.AccountDelegate{{AccountID: 1, Delegate: "carol"}}}
+	repo := &repositories.Repository{Account: aliceAccount{}}
+	svc := services.NewWebhookService(repo, &fakeUnitOfWork{}, services.NewAccessPolicy(delegates), services.WebhookPolicy{})
+
+	for owner, want := range map[string]bool{"alice": true, "carol": true, "dave": false} {
+		allowed, err := svc.CanDeliver(context.Background(), &models.WebhookSubscription{Owner: owner, AccountID: 1})
+		if err != nil || allowed != want {
+			t.Errorf("CanDeliver for %s = %v, %v, want %v", owner, allowed, err, want)
+		}
+	}
+
+	// carol's grant is revoked after subscribing
+	delegates.grants = nil
+	if allowed, err := svc.CanDeliver(context.Background(), &models.WebhookSubscription{Owner: "carol", AccountID: 1}); err != nil || allowed {
+		t.Errorf("CanDeliver for a revoked delegate = %v, %v, want false", allowed, err)
+	}
+}
@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+)
+
+// Stream event names sent to subscribers
+const (
+	EventEntry   = "entry"
+	EventBalance = "balance"
+	EventStatus  = "status"
+)
+
+// subscriberBuffer is how many messages a subscriber may fall behind before
+// it is disconnected and has to resume with Last-Event-ID
+const subscriberBuffer = 64
+
+// partsPerEvent is how many messages one outbox event may produce. A
+// message's ID is its event's ID times partsPerEvent plus its place in the
+// event, so each message has an ID of its own and IDs follow the order
+// messages are sent in.
+const partsPerEvent = 2
+
+func messageID(eventID int64, part int) int64 {
+	return eventID*partsPerEvent + int64(part)
+}
+
+// EventID is the ID of the outbox event that produced a message
+func EventID(messageID int64) int64 {
+	return messageID / partsPerEvent
+}
+
+// Message is a live update about one account. ID increases monotonically
+// and can be used to resume; see EventID.
+type Message struct {
+	ID        int64
+	Event     string
+	AccountID int64
+	Data      json.RawMessage
+}
+
+// Subscription receives the messages of one account on C. C is closed when
+// the subscriber falls too far behind or the subscription is closed.
+type Subscription struct {
+	C         <-chan Message
+	ch        chan Message
+	accountID int64
+	broker    *Broker
+	once      sync.Once
+}
+
+// Close unsubscribes; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker fans account messages out to in-process subscribers. It is fed by
+// the change feed listener, so subscribers share its single LISTEN
+// connection instead of each polling the database.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[int64]map[*Subscription]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe starts receiving messages for an account
+func (b *Broker) Subscribe(accountID int64) *Subscription {
+	ch := make(chan Message, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, accountID: accountID, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[accountID] == nil {
+		b.subscribers[accountID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[accountID][sub] = struct{}{}
+	return sub
+}
+
+func (b *Broker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove must be called with b.mu held for writing
+func (b *Broker) remove(sub *Subscription) {
+	subs := b.subscribers[sub.accountID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.accountID)
+	}
+	sub.once.Do(func() { close(sub.ch) })
+}
+
+// Publish sends msg to the account's subscribers without blocking.
+// Subscribers whose buffer is full are dropped.
+func (b *Broker) Publish(msg Message) {
+	var slow []*Subscription
+
+	b.mu.RLock()
+	for sub := range b.subscribers[msg.AccountID] {
+		select {
+		case sub.ch <- msg:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	if len(slow) > 0 {
+		b.mu.Lock()
+		for _, sub := range slow {
+			b.remove(sub)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// Name implements events.Sink
+func (b *Broker) Name() string {
+	return "stream"
+}
+
+// Deliver implements events.Sink by publishing the account messages
+// derived from each event
+func (b *Broker) Deliver(ctx context.Context, batch []models.OutboxEvent) error {
+	for _, event := range batch {
+		for _, msg := range Messages(event) {
+			b.Publish(msg)
+		}
+	}
+	return nil
+}
+
+// Messages converts an outbox event into the stream messages it produces.
+// Events that do not concern a single account produce none.
+func Messages(event models.OutboxEvent) []Message {
+	if event.AggregateType != events.AggregateAccount {
+		return nil
+	}
+
+	switch event.EventType {
+	case events.BalanceChanged:
+		var payload events.BalanceChangedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil
+		}
+
+		balance, _ := json.Marshal(struct {
+			Balance  int64  `json:"balance"`
+			Currency string `json:"currency"`
+		}{payload.Balance, payload.Currency})
+		messages := []Message{}
+		if payload.EntryID != 0 {
+			entry, _ := json.Marshal(struct {
+				ID        int64     `json:"id"`
+				AccountID int64     `json:"account_id"`
+				Amount    int64     `json:"amount"`
+				CreatedAt time.Time `json:"created_at"`
+			}{payload.EntryID, payload.AccountID, payload.Amount, event.CreatedAt})
+			messages = append(messages, Message{ID: messageID(event.ID, 0), Event: EventEntry, AccountID: event.AggregateID, Data: entry})
+		}
+		return append(messages, Message{ID: messageID(event.ID, 1), Event: EventBalance, AccountID: event.AggregateID, Data: balance})
+
+	case events.AccountFrozen, events.AccountUnfrozen, events.AccountClosed:
+		return []Message{{ID: messageID(event.ID, 0), Event: EventStatus, AccountID: event.AggregateID, Data: json.RawMessage(event.Payload)}}
+	}
+	return nil
+}
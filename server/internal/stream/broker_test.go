@@ -0,0 +1,72 @@
+package stream_test
+
+import (
+	"testing"
+
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/stream"
+)
+
+func TestBrokerFansOutPerAccount(t *testing.T) {
+	broker := stream.NewBroker()
+	first := broker.Subscribe(1)
+	second := broker.Subscribe(1)
+	other := broker.Subscribe(2)
+	defer first.Close()
+	defer second.Close()
+	defer other.Close()
+
+	broker.Publish(stream.Message{ID: 10, Event: stream.EventBalance, AccountID: 1})
+
+	for _, sub := range []*stream.Subscription{first, second} {
+		select {
+		case msg := <-sub.C:
+			if msg.ID != 10 {
+				t.Fatalf("got message %d, want 10", msg.ID)
+			}
+		default:
+			t.Fatal("subscriber of account 1 got no message")
+		}
+	}
+	select {
+	case msg := <-other.C:
+		t.Fatalf("subscriber of account 2 got message %d", msg.ID)
+	default:
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := stream.NewBroker()
+	sub := broker.Subscribe(1)
+	defer sub.Close()
+
+	for i := int64(1); i <= 1000; i++ {
+		broker.Publish(stream.Message{ID: i, Event: stream.EventBalance, AccountID: 1})
+	}
+
+	for range sub.C {
+	}
+	// Reaching here means the channel was closed instead of blocking Publish
+}
+
+func TestMessagesFromBalanceChanged(t *testing.T) {
+	event, err := events.NewEvent(events.BalanceChanged, events.AggregateAccount, 1, events.BalanceChangedPayload{
+		AccountID: 1,
+		Amount:    -50,
+		Balance:   150,
+		EntryID:   9,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.ID = 3
+
+	messages := stream.Messages(*event)
+	if len(messages) != 2 || messages[0].Event != stream.EventEntry || messages[1].Event != stream.EventBalance {
+		t.Fatalf("Messages() = %+v, want entry then balance", messages)
+	}
+	// Each message can be resumed from on its own
+	if messages[0].ID >= messages[1].ID || stream.EventID(messages[0].ID) != 3 || stream.EventID(messages[1].ID) != 3 {
+		t.Errorf("got message IDs %d and %d, want two increasing IDs of event 3", messages[0].ID, messages[1].ID)
+	}
+}
@@ -0,0 +1,131 @@
+// Package tracing sets up OpenTelemetry tracing. Spans start in the HTTP
+// and gRPC servers, continue through services via context.Context and end
+// in GORM queries, and are exported over OTLP or to stdout.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"simple_bank/server/internal/apperr"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies the server in traces
+const ServiceName = "simple-bank"
+
+// Exporters accepted by Setup
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends spans over gRPC to the collector named by the
+	// standard OTEL_EXPORTER_OTLP_* environment variables
+	ExporterOTLP = "otlp"
+)
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. Spans of a fraction sampleRatio of new traces are exported;
+// requests carrying a sampled parent are always traced. The returned
+// function flushes pending spans.
+func Setup(ctx context.Context, exporter string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		spanExporter, err = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for spans created by this server
+func Tracer() trace.Tracer {
+	return otel.Tracer("simple_bank/server")
+}
+
+// Start starts a span as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it. Only internal errors mark
+// the span as failed; errors reported to the client, such as insufficient
+// funds, are the expected outcome of the request.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if code := apperr.CodeOf(err); code == apperr.CodeInternal {
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(ErrorCodeKey.String(string(code)))
+		}
+	}
+	span.End()
+}
+
+// Attribute keys for bank domain values
+const (
+	AccountIDKey     = attribute.Key("bank.account.id")
+	FromAccountIDKey = attribute.Key("bank.transfer.from_account_id")
+	ToAccountIDKey   = attribute.Key("bank.transfer.to_account_id")
+	AmountBucketKey  = attribute.Key("bank.transfer.amount_bucket")
+	TransferIDKey    = attribute.Key("bank.transfer.id")
+	CurrencyKey      = attribute.Key("bank.currency")
+	RequestIDKey     = attribute.Key("bank.request.id")
+	ErrorCodeKey     = attribute.Key("bank.error.code")
+)
+
+// AmountBucket buckets an amount in minor units by order of magnitude,
+// such as "100-999", so traces can be grouped by transfer size without
+// recording exact amounts
+func AmountBucket(amount int64) string {
+	switch {
+	case amount < 100:
+		return "0-99"
+	case amount >= 1_000_000:
+		return ">=1000000"
+	}
+	low := int64(100)
+	for low*10 <= amount {
+		low *= 10
+	}
+	return strconv.FormatInt(low, 10) + "-" + strconv.FormatInt(low*10-1, 10)
+}
@@ -0,0 +1,52 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"simple_bank/server/internal/apperr"
+	"simple_bank/server/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAmountBucket(t *testing.T) {
+	cases := map[int64]string{
+		0:         "0-99",
+		99:        "0-99",
+		100:       "100-999",
+		4_200:     "1000-9999",
+		999_999:   "100000-999999",
+		1_000_000: ">=1000000",
+	}
+	for amount, want := range cases {
+		if got := tracing.AmountBucket(amount); got != want {
+			t.Errorf("AmountBucket(%d) = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestOnlyInternalErrorsFailSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	_, rejected := tracing.Start(context.Background(), "rejected")
+	tracing.End(rejected, apperr.New(apperr.CodeInsufficientFunds, "insufficient balance"))
+	_, failed := tracing.Start(context.Background(), "failed")
+	tracing.End(failed, errors.New("connection reset"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	if spans[0].Status().Code == codes.Error {
+		t.Error("a rejected transfer marked its span as failed")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Error("an internal error did not mark its span as failed")
+	}
+}
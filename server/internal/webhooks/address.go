@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrPrivateAddress is returned for subscriber hosts inside the network,
+// which webhooks must not be able to reach
+var ErrPrivateAddress = errors.New("webhooks: address is not publicly routable")
+
+// PublicAddress reports whether deliveries may be sent to addr. Loopback,
+// private, link-local, unspecified and multicast addresses are refused, so
+// a subscription cannot reach the server itself, the cloud metadata
+// endpoint or other internal services.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// CheckHost resolves host and fails with ErrPrivateAddress when any of its
+// addresses is not public
+func CheckHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !PublicAddress(addr) {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr, ErrPrivateAddress)
+		}
+	}
+	return nil
+}
+
+// dialPublic refuses connections to addresses that are not public. It runs
+// once the address is resolved, so DNS answers that changed since the URL
+// was validated are checked too.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("dial %s: %w", address, ErrPrivateAddress)
+	}
+	return nil
+}
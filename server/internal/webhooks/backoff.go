@@ -0,0 +1,28 @@
+package webhooks
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	baseBackoff = 10 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Backoff returns the wait before retrying after the given number of failed
+// attempts: exponential from 10s, capped at 6h, with up to 20% jitter so
+// retries to a recovering subscriber do not arrive all at once
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait + time.Duration(rand.Int64N(int64(wait)/5+1))
+}
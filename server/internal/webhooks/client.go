@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Client POSTs signed deliveries to subscriber URLs
+type Client struct {
+	http *http.Client
+	now  func() time.Time
+}
+
+// NewClient returns a client giving each delivery up to timeout. Unless
+// allowPrivate is set, it refuses to connect to addresses that are not
+// public; allowPrivate is meant for local development only.
+func NewClient(timeout time.Duration, allowPrivate bool) *Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = dialPublic
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect on our behalf, out of reach of the dialer's check
+	transport.Proxy = nil
+
+	return &Client{
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// Never follow redirects to addresses the subscriber did not register
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send delivers body to url. Any non-2xx response is an error.
+func (c *Client) Send(ctx context.Context, url, secret string, deliveryID int64, eventType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simple-bank-webhooks/1")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, c.now(), body))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("subscriber responded %d", resp.StatusCode)
+	}
+	return nil
+}
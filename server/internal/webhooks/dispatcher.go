@@ -0,0 +1,158 @@
+package webhooks
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"simple_bank/server/internal/health"
+	"simple_bank/server/internal/logging"
+	"simple_bank/server/internal/metrics"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+)
+
+// maxErrorLength bounds the last error kept on a delivery
+const maxErrorLength = 500
+
+// leaseMargin is added to the time a batch may take to send, so that a
+// lease outlives a batch whose subscribers all time out
+const leaseMargin = 30 * time.Second
+
+// Dispatcher sends queued deliveries, retrying failures with exponential
+// backoff and dead-lettering them after maxAttempts
+type Dispatcher struct {
+	uow          repositories.UnitOfWork
+	client       *Client
+	maxAttempts  int
+	batchSize    int
+	pollInterval time.Duration
+	lease        time.Duration
+	heartbeat    health.Heartbeat
+	log          *slog.Logger
+}
+
+func NewDispatcher(uow repositories.UnitOfWork, client *Client, maxAttempts, batchSize int, pollInterval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		uow:          uow,
+		client:       client,
+		maxAttempts:  maxAttempts,
+		batchSize:    batchSize,
+		pollInterval: pollInterval,
+		lease:        time.Duration(batchSize)*client.http.Timeout + leaseMargin,
+		log:          logging.For("webhooks"),
+	}
+}
+
+// Heartbeat beats every time Run polls for due deliveries
+func (d *Dispatcher) Heartbeat() *health.Heartbeat {
+	return &d.heartbeat
+}
+
+// Run dispatches deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			d.heartbeat.Beat()
+			n, err := d.DispatchOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					d.log.ErrorContext(ctx, "dispatching deliveries", "error", err)
+				}
+				break
+			}
+			if n < d.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce attempts a batch of due deliveries and returns its size.
+// The batch is leased in one transaction and each outcome recorded in
+// another, so no connection or row lock is held while subscribers respond.
+// Deliveries whose lease runs out before they are recorded are sent again.
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	// Postgres keeps microseconds, and Record matches the lease exactly
+	leasedUntil := now.Add(d.lease).Truncate(time.Microsecond)
+
+	var due []models.WebhookDelivery
+	err := d.uow.Do(ctx, func(tx *repositories.Repository) error {
+		var err error
+		due, err = tx.Delivery.LockDue(now, d.batchSize)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(due))
+		for i, delivery := range due {
+			ids[i] = delivery.ID
+		}
+		return tx.Delivery.Lease(ids, leasedUntil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Deliveries are due oldest first
+	if len(due) == 0 {
+		metrics.ObserveLag("webhooks", time.Time{})
+	} else {
+		metrics.ObserveLag("webhooks", due[0].NextAttemptAt)
+	}
+
+	for i := range due {
+		delivery := &due[i]
+		d.attempt(ctx, delivery)
+
+		var recorded bool
+		err := d.uow.Do(ctx, func(tx *repositories.Repository) error {
+			var err error
+			recorded, err = tx.Delivery.Record(delivery, leasedUntil)
+			return err
+		})
+		if err != nil {
+			return len(due), err
+		}
+		if !recorded {
+			d.log.WarnContext(ctx, "delivery changed while it was being sent", "delivery_id", delivery.ID)
+		}
+	}
+
+	return len(due), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	subscription := delivery.Subscription
+	err := d.client.Send(ctx, subscription.URL, subscription.Secret,
+		delivery.ID, delivery.EventType, delivery.Payload)
+
+	now := time.Now()
+	delivery.Attempts++
+	if err == nil {
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > maxErrorLength {
+		delivery.LastError = delivery.LastError[:maxErrorLength]
+	}
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = models.DeliveryStatusDead
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
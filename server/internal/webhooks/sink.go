@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"time"
+)
+
+// Authorizer decides whether a subscription may still receive the events of
+// its account
+type Authorizer interface {
+	CanDeliver(ctx context.Context, subscription *models.WebhookSubscription) (bool, error)
+}
+
+type sink struct {
+	subscriptions repositories.WebhookSubscriptionRepository
+	deliveries    repositories.WebhookDeliveryRepository
+	authorizer    Authorizer
+}
+
+// NewSink returns an outbox sink that queues a webhook delivery for every
+// subscription matching an event whose owner authorizer still lets see the
+// account. The dispatcher sends them later, so a slow subscriber never
+// holds up the outbox relay.
+func NewSink(subscriptions repositories.WebhookSubscriptionRepository, deliveries repositories.WebhookDeliveryRepository, authorizer Authorizer) events.Sink {
+	return &sink{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		authorizer:    authorizer,
+	}
+}
+
+func (s *sink) Name() string {
+	return "webhooks"
+}
+
+func (s *sink) Deliver(ctx context.Context, batch []models.OutboxEvent) error {
+	for _, event := range batch {
+		if err := s.enqueue(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue maps a domain event to the webhook event it triggers, if any
+func (s *sink) enqueue(ctx context.Context, event models.OutboxEvent) error {
+	switch event.EventType {
+	case events.TransferCompleted:
+		var payload events.TransferCompletedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+		return s.enqueueFor(ctx, event, payload.ToAccountID, EventTransferReceived, nil)
+
+	case events.BalanceChanged:
+		var payload events.BalanceChangedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return err
+		}
+		previous := payload.Balance - payload.Amount
+		// Only notify when the balance crosses the threshold, not on every
+		// change while it stays below
+		crossed := func(subscription models.WebhookSubscription) bool {
+			threshold := subscription.BalanceThreshold
+			return threshold != nil && previous >= *threshold && payload.Balance < *threshold
+		}
+		return s.enqueueFor(ctx, event, payload.AccountID, EventBalanceBelowThreshold, crossed)
+
+	case events.AccountFrozen:
+		return s.enqueueFor(ctx, event, event.AggregateID, EventAccountFrozen, nil)
+	}
+	return nil
+}
+
+func (s *sink) enqueueFor(ctx context.Context, event models.OutboxEvent, accountID int64, eventType string, match func(models.WebhookSubscription) bool) error {
+	subscriptions, err := s.subscriptions.GetByAccountAndEvent(accountID, eventType)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if match != nil && !match(subscription) {
+			continue
+		}
+		allowed, err := s.authorizer.CanDeliver(ctx, &subscription)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			continue
+		}
+
+		body, err := models.NewJSON(Envelope{
+			ID:        event.ID,
+			Type:      eventType,
+			AccountID: accountID,
+			CreatedAt: event.CreatedAt,
+			Data:      json.RawMessage(event.Payload),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = s.deliveries.Enqueue(&models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			OutboxEventID:  event.ID,
+			EventType:      eventType,
+			Payload:        body,
+			Status:         models.DeliveryStatusPending,
+			NextAttemptAt:  time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
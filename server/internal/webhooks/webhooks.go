@@ -0,0 +1,41 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types subscribers can register for
+const (
+	EventTransferReceived      = "transfer.received"
+	EventBalanceBelowThreshold = "balance.below_threshold"
+	EventAccountFrozen         = "account.frozen"
+)
+
+// EventTypes lists every event a subscription may listen for
+var EventTypes = []string{
+	EventTransferReceived,
+	EventBalanceBelowThreshold,
+	EventAccountFrozen,
+}
+
+// ValidEventType reports whether eventType can be subscribed to
+func ValidEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Envelope is the JSON body POSTed to subscribers
+type Envelope struct {
+	// ID identifies the underlying event; receivers should dedupe on it
+	// since deliveries are at-least-once
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	AccountID int64           `json:"account_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
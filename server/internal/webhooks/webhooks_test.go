@@ -0,0 +1,237 @@
+package webhooks_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"simple_bank/server/internal/events"
+	"simple_bank/server/internal/models"
+	"simple_bank/server/internal/repositories"
+	"simple_bank/server/internal/webhooks"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	header := webhooks.Sign("whsec_test", now, body)
+
+	if err := webhooks.Verify("whsec_test", header, body, time.Minute, now); err != nil {
+		t.Fatalf("Verify() = %v, want nil", err)
+	}
+	if err := webhooks.Verify("whsec_other", header, body, time.Minute, now); !errors.Is(err, webhooks.ErrInvalidSignature) {
+		t.Fatalf("Verify() with wrong secret = %v, want %v", err, webhooks.ErrInvalidSignature)
+	}
+	if err := webhooks.Verify("whsec_test", header, []byte(`{"id":2}`), time.Minute, now); !errors.Is(err, webhooks.ErrInvalidSignature) {
+		t.Fatalf("Verify() with tampered body = %v, want %v", err, webhooks.ErrInvalidSignature)
+	}
+	if err := webhooks.Verify("whsec_test", header, body, time.Minute, now.Add(2*time.Minute)); !errors.Is(err, webhooks.ErrInvalidSignature) {
+		t.Fatalf("Verify() of stale signature = %v, want %v", err, webhooks.ErrInvalidSignature)
+	}
+}
+
+func TestClientSendsSignedDelivery(t *testing.T) {
+	body := []byte(`{"id":42,"type":"transfer.received"}`)
+
+	var received http.Header
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	client := webhooks.NewClient(time.Second, true)
+	if err := client.Send(context.Background(), receiver.URL, "whsec_test", 7, webhooks.EventTransferReceived, body); err != nil {
+		t.Fatalf("Send() = %v, want nil", err)
+	}
+
+	if got := received.Get(webhooks.EventHeader); got != webhooks.EventTransferReceived {
+		t.Errorf("%s = %q, want %q", webhooks.EventHeader, got, webhooks.EventTransferReceived)
+	}
+	if got := received.Get(webhooks.DeliveryHeader); got != "7" {
+		t.Errorf("%s = %q, want %q", webhooks.DeliveryHeader, got, "7")
+	}
+	if err := webhooks.Verify("whsec_test", received.Get(webhooks.SignatureHeader), receivedBody, time.Minute, time.Now()); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+}
+
+func TestClientFailsOnErrorStatus(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	client := webhooks.NewClient(time.Second, true)
+	if err := client.Send(context.Background(), receiver.URL, "whsec_test", 1, webhooks.EventAccountFrozen, []byte(`{}`)); err == nil {
+		t.Fatal("Send() = nil, want error for 503 response")
+	}
+}
+
+func TestBackoffGrowsAndIsCapped(t *testing.T) {
+	if got := webhooks.Backoff(1); got < 10*time.Second || got > 12*time.Second {
+		t.Errorf("Backoff(1) = %v, want 10s to 12s", got)
+	}
+	if got := webhooks.Backoff(3); got < 40*time.Second || got > 48*time.Second {
+		t.Errorf("Backoff(3) = %v, want 40s to 48s", got)
+	}
+	if got := webhooks.Backoff(100); got > 6*time.Hour+6*time.Hour/5 {
+		t.Errorf("Backoff(100) = %v, want at most the 6h cap plus jitter", got)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.215.14":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fc00::1":          false,
+		"0.0.0.0":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := webhooks.PublicAddress(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	client := webhooks.NewClient(time.Second, false)
+	err := client.Send(context.Background(), receiver.URL, "whsec_test", 1, webhooks.EventAccountFrozen, []byte(`{}`))
+	if !errors.Is(err, webhooks.ErrPrivateAddress) || called {
+		t.Fatalf("Send() to %s = %v, want %v", receiver.URL, err, webhooks.ErrPrivateAddress)
+	}
+}
+
+// leasedDeliveries is a unit of work over one due delivery that records
+// whether a transaction is open
+type leasedDeliveries struct {
+	repositories.WebhookDeliveryRepository
+	due         models.WebhookDelivery
+	inTx        atomic.Bool
+	leasedUntil time.Time
+	recorded    *models.WebhookDelivery
+}
+
+func (l *leasedDeliveries) Do(ctx context.Context, fn func(tx *repositories.Repository) error) error {
+	l.inTx.Store(true)
+	defer l.inTx.Store(false)
+	return fn(&repositories.Repository{Delivery: l})
+}
+
+func (l *leasedDeliveries) LockDue(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	return []models.WebhookDelivery{l.due}, nil
+}
+
+func (l *leasedDeliveries) Lease(ids []int64, until time.Time) error {
+	l.leasedUntil = until
+	return nil
+}
+
+func (l *leasedDeliveries) Record(delivery *models.WebhookDelivery, leasedUntil time.Time) (bool, error) {
+	if !leasedUntil.Equal(l.leasedUntil) {
+		return false, nil
+	}
+	l.recorded = delivery
+	return true, nil
+}
+
+func TestDispatcherSendsOutsideTransactions(t *testing.T) {
+	deliveries := &leasedDeliveries{}
+	var sentInTx atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sentInTx.Store(deliveries.inTx.Load())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	deliveries.due = models.WebhookDelivery{
+		ID:           3,
+		EventType:    webhooks.EventAccountFrozen,
+		Payload:      models.JSON(`{}`),
+		Status:       models.DeliveryStatusPending,
+		Subscription: models.WebhookSubscription{URL: receiver.URL, Secret: "whsec_test"},
+	}
+	dispatcher := webhooks.NewDispatcher(deliveries, webhooks.NewClient(time.Second, true), 3, 10, time.Second)
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DispatchOnce() = %d, %v, want 1 delivery", n, err)
+	}
+	if sentInTx.Load() {
+		t.Error("the delivery was sent with a transaction open")
+	}
+	if !deliveries.leasedUntil.After(time.Now()) {
+		t.Errorf("the delivery was leased until %v", deliveries.leasedUntil)
+	}
+	if deliveries.recorded == nil || deliveries.recorded.Status != models.DeliveryStatusDelivered || deliveries.recorded.Attempts != 1 {
+		t.Errorf("recorded %+v, want one successful attempt", deliveries.recorded)
+	}
+}
+
+// subscribed holds an account's subscriptions
+type subscribed struct {
+	repositories.WebhookSubscriptionRepository
+	subscriptions []models.WebhookSubscription
+}
+
+func (s subscribed) GetByAccountAndEvent(accountID int64, eventType string) ([]models.WebhookSubscription, error) {
+	return s.subscriptions, nil
+}
+
+// queuedDeliveries keeps the deliveries queued
+type queuedDeliveries struct {
+	repositories.WebhookDeliveryRepository
+	queued []*models.WebhookDelivery
+}
+
+func (q *queuedDeliveries) Enqueue(delivery *models.WebhookDelivery) error {
+	q.queued = append(q.queued, delivery)
+	return nil
+}
+
+// ownersOnly lets only alice receive events
+type ownersOnly struct{}
+
+func (ownersOnly) CanDeliver(ctx context.Context, subscription *models.WebhookSubscription) (bool, error) {
+	return subscription.Owner == "alice", nil
+}
+
+func TestSinkSkipsSubscribersWhoLostAccess(t *testing.T) {
+	subscriptions := subscribed{subscriptions: []models.WebhookSubscription{
+		{ID: 1, Owner: "alice", AccountID: 1},
+		{ID: 2, Owner: "revoked", AccountID: 1},
+	}}
+	deliveries := &queuedDeliveries{}
+	sink := webhooks.NewSink(subscriptions, deliveries, ownersOnly{})
+
+	err := sink.Deliver(context.Background(), []models.OutboxEvent{
+		{ID: 9, EventType: events.AccountFrozen, AggregateType: events.AggregateAccount, AggregateID: 1, Payload: models.JSON(`{}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries.queued) != 1 || deliveries.queued[0].SubscriptionID != 1 {
+		t.Fatalf("queued %+v, want only alice's subscription", deliveries.queued)
+	}
+}